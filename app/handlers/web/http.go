@@ -6,16 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
 	"net/http"
 	"smart-contract-service/app/usecase"
 	"smart-contract-service/configuration"
 	"smart-contract-service/models"
-)
-
-const (
-	EllipticAlgorithm = "elliptic"
-	HashAlgorithm     = "hash"
-	EddsaAlgorithm    = "eddsa"
+	models2 "smart-contract-service/models/circuit"
 )
 
 type HTTP struct {
@@ -157,6 +153,19 @@ func (h *HTTP) PingHandler(c echo.Context) (err error) {
 	})
 }
 
+func (h *HTTP) ReadinessHandler(c echo.Context) (err error) {
+	if !h.uc.IsReady() {
+		return c.JSON(http.StatusServiceUnavailable, models.Response{
+			Code:    http.StatusServiceUnavailable,
+			Message: "Circuit keys are still loading",
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+	})
+}
+
 func (h *HTTP) GetProof(c echo.Context) (err error) {
 	request := new(models.CustomerIdRequest)
 	if err = c.Bind(request); err != nil {
@@ -166,31 +175,149 @@ func (h *HTTP) GetProof(c echo.Context) (err error) {
 		})
 	}
 
-	var proof *models.ProofResponse
-	switch algo := request.Algo; algo {
-	case EllipticAlgorithm:
-		proof, err = h.uc.GetEllipticProof(request.Id)
-	case HashAlgorithm:
-		proof, err = h.uc.GetHashProof(request.Id)
-	case EddsaAlgorithm:
-		proof, err = h.uc.GetEddsaProof(request.Id)
-	default:
-		err = fmt.Errorf("algorithm not found : %s", algo)
+	deprecationNotice(c, request.Algo)
+	proof, err := h.uc.GetProof(request.Algo, request.Id, request.Context)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    proof,
+	})
+}
+
+func (h *HTTP) GetPaymentProof(c echo.Context) (err error) {
+	var request *models.PaymentProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
 	}
 
+	request.PartnerId = sessionPartner(c)
+	proof, err := h.uc.GetPaymentProof(request)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.Response{
-			Code:    http.StatusInternalServerError,
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
 			Message: err.Error(),
 		})
 	}
 	return c.JSON(http.StatusOK, models.Response{
 		Code:    http.StatusOK,
-		Message: "Success",
+		Message: models.SUCCESS,
+		Data:    proof,
+	})
+}
+
+func (h *HTTP) GetRangeProof(c echo.Context) (err error) {
+	var request *models.PaymentProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	request.PartnerId = sessionPartner(c)
+	proof, err := h.uc.GetRangeProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
 		Data:    proof,
 	})
 }
 
+func (h *HTTP) GetBatchProof(c echo.Context) (err error) {
+	var request *models.BatchProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	deprecationNotice(c, request.Algo)
+	request.PartnerId = sessionPartner(c)
+	proofs, err := h.uc.GetBatchProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    proofs,
+	})
+}
+
+func (h *HTTP) SubmitProofJob(c echo.Context) (err error) {
+	var request *models.ProofJobRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	deprecationNotice(c, request.Algo)
+	request.PartnerId = sessionPartner(c)
+	job, err := h.uc.SubmitProofJob(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusAccepted, models.Response{
+		Code:    http.StatusAccepted,
+		Message: models.SUCCESS,
+		Data:    job,
+	})
+}
+
+func (h *HTTP) GetProofJob(c echo.Context) (err error) {
+	request := new(models.ProofJobIdRequest)
+	if err = c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	request.PartnerId = sessionPartner(c)
+	job, err := h.uc.GetProofJob(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    job,
+	})
+}
+
 func (h *HTTP) VerifyProof(c echo.Context) (err error) {
 	var request *models.ProofRequest
 	if err = c.Bind(&request); err != nil {
@@ -200,27 +327,308 @@ func (h *HTTP) VerifyProof(c echo.Context) (err error) {
 		})
 	}
 
-	var valid bool
-	switch algo := request.Algo; algo {
-	case EllipticAlgorithm:
-		_, valid = h.uc.VerifyEllipticProof(request.Proof)
-	case HashAlgorithm:
-		_, valid = h.uc.VerifyHashProof(request.Proof)
-	case EddsaAlgorithm:
-		_, valid = h.uc.VerifyEddsaProof(request.Proof)
-	default:
-		valid = false
+	deprecationNotice(c, request.Algo)
+	result, err := h.uc.VerifyProof(request.Algo, request.Proof)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
 	}
-
-	if !valid {
+	if !result.Valid {
 		return c.JSON(http.StatusUnauthorized, models.Response{
 			Code:    http.StatusUnauthorized,
 			Message: "Proof not knowledgeable",
+			Data:    result,
 		})
 	}
 	return c.JSON(http.StatusOK, models.Response{
 		Code:    http.StatusOK,
 		Message: "Success",
+		Data:    result,
+	})
+}
+
+func (h *HTTP) RevokeProof(c echo.Context) (err error) {
+	var request *models.ProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	request.PartnerId = sessionPartner(c)
+	revocation, err := h.uc.RevokeProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    revocation,
+	})
+}
+
+// VerifyBatchProof answers 200 whatever the proofs, each result tells whether its proof is valid
+func (h *HTTP) VerifyBatchProof(c echo.Context) (err error) {
+	var request *models.BatchVerifyRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	data, err := h.uc.VerifyBatchProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    data,
+	})
+}
+
+func (h *HTTP) AggregateProof(c echo.Context) (err error) {
+	var request *models.AggregateProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	data, err := h.uc.AggregateProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    data,
+	})
+}
+
+func (h *HTTP) VerifyAggregateProof(c echo.Context) (err error) {
+	var request *models.AggregateVerifyRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	data, err := h.uc.VerifyAggregateProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    data,
+	})
+}
+
+func (h *HTTP) GetProofCalldata(c echo.Context) (err error) {
+	var request *models.ProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	data, err := h.uc.GetProofCalldata(request.Proof)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    data,
+	})
+}
+
+func (h *HTTP) VerifyExternalProof(c echo.Context) (err error) {
+	var request *models.ExternalProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	deprecationNotice(c, request.Algo)
+	request.PartnerId = sessionPartner(c)
+	proof, err := h.uc.VerifyExternalProof(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    proof,
+	})
+}
+
+func (h *HTTP) GetMembershipRoot(c echo.Context) (err error) {
+	root, err := h.uc.GetMembershipRoot()
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    root,
+	})
+}
+
+func (h *HTTP) GetCircuitArtifact(c echo.Context) (err error) {
+	request := new(models.CircuitArtifactRequest)
+	if err = c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	deprecationNotice(c, request.Algo)
+	fileName, err := h.uc.GetCircuitArtifact(request.Algo, request.Artifact, request.Backend)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.File(fileName)
+}
+
+func (h *HTTP) GetPublicSchema(c echo.Context) (err error) {
+	request := new(models.CircuitSchemaRequest)
+	if err = c.Bind(request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	deprecationNotice(c, request.Algo)
+	schema, err := h.uc.GetPublicSchema(request.Algo)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, models.Response{
+		Code:    http.StatusOK,
+		Message: models.SUCCESS,
+		Data:    schema,
+	})
+}
+
+func (h *HTTP) RegisterKey(c echo.Context) (err error) {
+	var request *models.KeyRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	key, err := h.uc.RegisterKey(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusCreated, models.Response{
+		Code:    http.StatusCreated,
+		Message: models.SUCCESS,
+		Data:    key,
+	})
+}
+
+func (h *HTTP) EnrollCommitment(c echo.Context) (err error) {
+	var request *models.CommitmentRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	commitment, err := h.uc.EnrollCommitment(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusCreated, models.Response{
+		Code:    http.StatusCreated,
+		Message: models.SUCCESS,
+		Data:    commitment,
+	})
+}
+
+func (h *HTTP) IssueCredential(c echo.Context) (err error) {
+	var request *models.CredentialRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+
+	credential, err := h.uc.IssueCredential(request)
+	if err != nil {
+		code := proofErrorStatus(err)
+		return c.JSON(code, models.Response{
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusCreated, models.Response{
+		Code:    http.StatusCreated,
+		Message: models.SUCCESS,
+		Data:    credential,
 	})
 }
 
@@ -255,20 +663,46 @@ func (h *HTTP) PaymentTransactionWithProof(c echo.Context) (err error) {
 			Message: err.Error(),
 		})
 	}
-	var valid bool
-	var userId string
-	switch algo := request.Algo; algo {
-	case EllipticAlgorithm:
-		userId, valid = h.uc.VerifyEllipticProof(request.Proof)
-	case HashAlgorithm:
-		userId, valid = h.uc.VerifyHashProof(request.Proof)
-	case EddsaAlgorithm:
-		userId, valid = h.uc.VerifyEddsaProof(request.Proof)
-	default:
-		valid = false
+	deprecationNotice(c, request.Algo)
+	return h.payWithProof(c, request)
+}
+
+// PaymentTransactionWithRangeProof creates the payment once a range proof shows the
+// amount is in the partner range and covered by the customer balance
+func (h *HTTP) PaymentTransactionWithRangeProof(c echo.Context) (err error) {
+	var request *models.PaymentTransactionWithProofRequest
+	if err = c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
 	}
+	request.Algo = models2.RangeAlgorithm
+	return h.payWithProof(c, request)
+}
 
-	if !valid {
+// payWithProof spends the proof of the request for its payment intent, then creates the payment
+func (h *HTTP) payWithProof(c echo.Context, request *models.PaymentTransactionWithProofRequest) (err error) {
+	// the proof is spent before the payment, a failed payment needs a new proof
+	userId, err := h.uc.SpendProof(request.Algo, request.Proof, &models.PaymentIntent{
+		PartnerReferenceNo: request.PartnerReferenceNo,
+		Amount:             request.Amount.Value,
+		Currency:           request.Amount.Currency,
+		ExternalId:         c.Request().Header.Get("X-EXTERNAL-ID"),
+	})
+	if errors.Is(err, usecase.ErrProofSpent) {
+		return c.JSON(http.StatusConflict, models.Response{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, models2.ErrPaymentNotSupported) || errors.Is(err, models2.ErrAlgorithmNotFound) {
+		return c.JSON(http.StatusBadRequest, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusUnauthorized, models.Response{
 			Code:    http.StatusUnauthorized,
 			Message: errors.New("Proof not valid ").Error(),
@@ -293,3 +727,54 @@ func (h *HTTP) PaymentTransactionWithProof(c echo.Context) (err error) {
 		Data:    id,
 	})
 }
+
+// sessionPartner returns the partner of the access token, empty on the open routes
+func sessionPartner(c echo.Context) string {
+	session, _ := c.Get("session").(models.JwtCustomClaims)
+	return session.ID
+}
+
+// deprecationNotice warns callers still using a deprecated algorithm, the request is served as usual
+func deprecationNotice(c echo.Context, algo string) {
+	def, err := models2.Lookup(algo)
+	if err != nil || def.Deprecated == "" {
+		return
+	}
+	log.Warnf("deprecated algorithm %s requested on %s", def.Name, c.Path())
+	c.Response().Header().Set("Deprecation", "true")
+	c.Response().Header().Set("Warning", fmt.Sprintf(`299 - "algorithm %s is deprecated, use %s"`, def.Name, def.Deprecated))
+}
+
+// proofErrorStatus maps usecase proof errors to a http status
+func proofErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models2.ErrAlgorithmNotFound), errors.Is(err, usecase.ErrInvalidKey),
+		errors.Is(err, models2.ErrBackendNotSupported), errors.Is(err, models2.ErrCurveNotSupported),
+		errors.Is(err, usecase.ErrInvalidBatch), errors.Is(err, models2.ErrAggregateNotSupported),
+		errors.Is(err, models2.ErrIntentMissing), errors.Is(err, models2.ErrInvalidAmount),
+		errors.Is(err, models2.ErrInvalidPublicInput), errors.Is(err, models2.ErrPaymentNotSupported),
+		errors.Is(err, usecase.ErrJobNotSupported), errors.Is(err, usecase.ErrNullifierMissing):
+		return http.StatusBadRequest
+	case errors.Is(err, models2.ErrSigningKeyMissing), errors.Is(err, models2.ErrCommitmentMissing),
+		errors.Is(err, models2.ErrCommitmentMismatch), errors.Is(err, models2.ErrBirthDateMissing),
+		errors.Is(err, models2.ErrNotMember), errors.Is(err, models2.ErrRangeMissing),
+		errors.Is(err, models2.ErrInvalidRange):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, models2.ErrPolicyNotSatisfied), errors.Is(err, models2.ErrAmountOutOfRange),
+		errors.Is(err, models2.ErrBalanceInsufficient), errors.Is(err, usecase.ErrConsentMissing),
+		errors.Is(err, usecase.ErrProofNotOwned):
+		return http.StatusForbidden
+	case errors.Is(err, models2.ErrIssuerKeyMissing), errors.Is(err, models2.ErrIssuerKeyRevoked),
+		errors.Is(err, usecase.ErrJobQueueFull):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, usecase.ErrCustomerNotFound), errors.Is(err, usecase.ErrArtifactNotFound),
+		errors.Is(err, usecase.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrInvalidProof), errors.Is(err, usecase.ErrInvalidProofToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, usecase.ErrKeysNotReady), errors.Is(err, usecase.ErrMembershipNotReady):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -39,7 +39,7 @@ func BenchmarkHTTP_RSAPingHandler(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -74,7 +74,7 @@ func BenchmarkHTTP_HMACPingHandler(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -109,7 +109,7 @@ func BenchmarkHTTP_RSAVerifyEllipticProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -147,7 +147,7 @@ func BenchmarkHTTP_HMACVerifyEllipticProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -185,7 +185,7 @@ func BenchmarkHTTP_RSAVerifyHashProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -223,7 +223,7 @@ func BenchmarkHTTP_HMACVerifyHashProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -261,7 +261,7 @@ func BenchmarkHTTP_RSAVerifyEddsaProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -299,7 +299,7 @@ func BenchmarkHTTP_HMACVerifyEddsaProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -337,7 +337,7 @@ func BenchmarkHTTP_GetEllipticProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -366,7 +366,7 @@ func BenchmarkHTTP_GetHashProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -395,7 +395,7 @@ func BenchmarkHTTP_GetEddsaProof(b *testing.B) {
 			Path:        "/home/ramadhoni/GolandProjects/zkSnark",
 		}
 		configMain.Load()
-		handler := newHandler(configMain)
+		handler := newHandler(b, configMain)
 
 		b.ReportAllocs()
 		b.ResetTimer()
@@ -417,7 +417,8 @@ func BenchmarkHTTP_GetEddsaProof(b *testing.B) {
 	})
 }
 
-func newHandler(configMain configuration.ServiceApp) *HTTP {
+func newHandler(b *testing.B, configMain configuration.ServiceApp) *HTTP {
+	b.Helper()
 
 	dbConn := configuration.InitSingleDB(configMain.Config.PostgreConnection, configMain.Config.LogMode)
 	redisClient := redis.NewClient(&redis.Options{
@@ -427,8 +428,14 @@ func newHandler(configMain configuration.ServiceApp) *HTTP {
 	})
 	repoDb := repo.NewDatabaseConnection(dbConn)
 	repoRedis := repo.NewRedisConnection(redisClient)
-
-	uc := usecase.NewUsecase(repoRedis, repoDb, configMain.Config)
+	keyStore := repo.NewKeyStore(configMain.Config)
+	if err := keyStore.Load(); err != nil {
+		b.Fatal(err)
+	}
+	jobQueue := repo.NewLocalJobQueue(configMain.Config.ProofJobQueueSize, configMain.Config.ProofJobWorkers)
+	membershipTree := repo.NewMembershipTree(configMain.Config.MembershipRootHistory)
+
+	uc := usecase.NewUsecase(repoRedis, repoDb, keyStore, jobQueue, membershipTree, configMain.Config)
 
 	handler := NewHTTP(configMain.Config, uc)
 	return handler
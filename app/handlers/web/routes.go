@@ -30,7 +30,12 @@ func (route *Routes) RegisterServices(r *echo.Echo, handler *HTTP) {
 	openRoutes.POST("/signup", handler.SignUp)
 	openRoutes.POST("/token", handler.Token)
 	openRoutes.POST("/token-hmac", handler.TokenHMAC)
+	openRoutes.GET("/ready", handler.ReadinessHandler)
 	openRoutes.GET("/proof", handler.GetProof)
+	openRoutes.POST("/proof/calldata", handler.GetProofCalldata)
+	openRoutes.GET("/circuit/:algo/schema", handler.GetPublicSchema)
+	openRoutes.GET("/circuit/:algo/:artifact", handler.GetCircuitArtifact)
+	openRoutes.GET("/membership/root", handler.GetMembershipRoot)
 	apiRoutes.POST("/rsa/login", handler.Login)
 	hmacRoutes.POST("/hmac/login", handler.Login)
 	apiRoutes.POST("/refresh", handler.RefreshToken)
@@ -39,8 +44,33 @@ func (route *Routes) RegisterServices(r *echo.Echo, handler *HTTP) {
 	accessTokenRoute.GET("/hmac/ping", handler.PingHandler, middleware2.SignatureHMACValidator(route.config))
 	accessTokenRoute.POST("/rsa/proof", handler.VerifyProof, middleware2.RSASignatureValidator(route.config))
 	accessTokenRoute.POST("/hmac/proof", handler.VerifyProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/revoke", handler.RevokeProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/revoke", handler.RevokeProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/verify-batch", handler.VerifyBatchProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/verify-batch", handler.VerifyBatchProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/batch", handler.GetBatchProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/batch", handler.GetBatchProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/aggregate", handler.AggregateProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/aggregate", handler.AggregateProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/aggregate/verify", handler.VerifyAggregateProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/aggregate/verify", handler.VerifyAggregateProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/external", handler.VerifyExternalProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/external", handler.VerifyExternalProof, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/proof/jobs", handler.SubmitProofJob, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/proof/jobs", handler.SubmitProofJob, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.GET("/rsa/proof/jobs/:id", handler.GetProofJob, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.GET("/hmac/proof/jobs/:id", handler.GetProofJob, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/keys", handler.RegisterKey, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/keys", handler.RegisterKey, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/commitments", handler.EnrollCommitment, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/commitments", handler.EnrollCommitment, middleware2.SignatureHMACValidator(route.config))
+	accessTokenRoute.POST("/rsa/credentials", handler.IssueCredential, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/hmac/credentials", handler.IssueCredential, middleware2.SignatureHMACValidator(route.config))
 	accessTokenRoute.POST("/transaction/payment", handler.PaymentTransaction, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/proof/payment", handler.GetPaymentProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/proof/range", handler.GetRangeProof, middleware2.RSASignatureValidator(route.config))
 	accessTokenRoute.POST("/transaction/payment-proof", handler.PaymentTransactionWithProof, middleware2.RSASignatureValidator(route.config))
+	accessTokenRoute.POST("/transaction/payment-range-proof", handler.PaymentTransactionWithRangeProof, middleware2.RSASignatureValidator(route.config))
 }
 
 func (route *Routes) setMiddleware(rGroup *echo.Group) {
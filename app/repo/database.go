@@ -3,6 +3,7 @@ package repo
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"smart-contract-service/app/usecase"
 	"smart-contract-service/models"
 	"time"
@@ -69,3 +70,146 @@ func (db *DatabaseConnection) InsertPayment(input *models.Payment) (id string, e
 	}).Error
 	return id, err
 }
+
+func (db *DatabaseConnection) UpdateCustomerCommitment(id string, commitment string, salt string) (err error) {
+	timeNow := time.Now()
+	err = db.client.Model(&models.Customer{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"commitment":      commitment,
+		"commitment_salt": salt,
+		"updated_at":      &timeNow,
+	}).Error
+	return
+}
+
+func (db *DatabaseConnection) GetCustomerKey(customerId string) (data *models.CustomerKey, err error) {
+	err = db.client.Model(&models.CustomerKey{}).Where("customer_id = ?", customerId).Find(&data).Error
+	return
+}
+
+func (db *DatabaseConnection) SaveCustomerKey(input *models.CustomerKey) (id string, err error) {
+	id = uuid.New().String()
+	timeNow := time.Now()
+	// a customer has a single key, registering again rotates it
+	err = db.client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"public_key": input.PublicKey, "private_key": input.PrivateKey, "updated_at": &timeNow}),
+	}).Create(&models.CustomerKey{
+		Id:         id,
+		CustomerId: input.CustomerId,
+		PublicKey:  input.PublicKey,
+		PrivateKey: input.PrivateKey,
+		CreatedAt:  &timeNow,
+		UpdatedAt:  nil,
+	}).Error
+	return id, err
+}
+
+func (db *DatabaseConnection) InsertProofJob(input *models.ProofJob) (id string, err error) {
+	id = uuid.New().String()
+	timeNow := time.Now()
+	err = db.client.Create(&models.ProofJob{
+		Id:         id,
+		Algo:       input.Algo,
+		CustomerId: input.CustomerId,
+		PartnerId:  input.PartnerId,
+		Status:     models.ProofJobQueued,
+		CreatedAt:  &timeNow,
+		UpdatedAt:  &timeNow,
+	}).Error
+	return id, err
+}
+
+func (db *DatabaseConnection) GetProofJob(id string) (data *models.ProofJob, err error) {
+	err = db.client.Model(&models.ProofJob{}).Where("id = ?", id).Find(&data).Error
+	return
+}
+
+// ClaimProofJob moves a queued job to running, it reports false when the job was
+// already claimed so a job runs once even when enqueued twice
+func (db *DatabaseConnection) ClaimProofJob(id string) (claimed bool, err error) {
+	timeNow := time.Now()
+	result := db.client.Model(&models.ProofJob{}).Where("id = ? AND status = ?", id, models.ProofJobQueued).Updates(map[string]interface{}{
+		"status":     models.ProofJobRunning,
+		"updated_at": &timeNow,
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (db *DatabaseConnection) FinishProofJob(id string, status string, hash string, message string) (err error) {
+	timeNow := time.Now()
+	err = db.client.Model(&models.ProofJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"hash":       hash,
+		"error":      message,
+		"updated_at": &timeNow,
+	}).Error
+	return
+}
+
+// RequeueProofJobs puts back the jobs interrupted by a restart and returns every queued job, oldest first
+func (db *DatabaseConnection) RequeueProofJobs() (ids []string, err error) {
+	timeNow := time.Now()
+	err = db.client.Model(&models.ProofJob{}).Where("status = ?", models.ProofJobRunning).Updates(map[string]interface{}{
+		"status":     models.ProofJobQueued,
+		"updated_at": &timeNow,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.client.Model(&models.ProofJob{}).Where("status = ?", models.ProofJobQueued).Order("created_at").Pluck("id", &ids).Error
+	return
+}
+
+// SpendNullifier records a nullifier as spent, it reports false when it already was or the
+// customer already paid the context, so concurrent payments with the same proof cannot
+// both succeed
+func (db *DatabaseConnection) SpendNullifier(input *models.SpentNullifier) (recorded bool, err error) {
+	timeNow := time.Now()
+	result := db.client.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SpentNullifier{
+		Circuit:    input.Circuit,
+		Nullifier:  input.Nullifier,
+		CustomerId: input.CustomerId,
+		Context:    input.Context,
+		CreatedAt:  &timeNow,
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeNullifier records a nullifier as revoked, spent or not, proofs carrying it no
+// longer verify nor spend
+func (db *DatabaseConnection) RevokeNullifier(input *models.SpentNullifier) (err error) {
+	timeNow := time.Now()
+	return db.client.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "circuit"}, {Name: "nullifier"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"revoked": true}),
+	}).Create(&models.SpentNullifier{
+		Circuit:    input.Circuit,
+		Nullifier:  input.Nullifier,
+		CustomerId: input.CustomerId,
+		Revoked:    true,
+		CreatedAt:  &timeNow,
+	}).Error
+}
+
+func (db *DatabaseConnection) IsNullifierRevoked(circuit string, nullifier string) (revoked bool, err error) {
+	var count int64
+	err = db.client.Model(&models.SpentNullifier{}).Where("circuit = ? AND nullifier = ? AND revoked", circuit, nullifier).Count(&count).Error
+	return count > 0, err
+}
+
+// GetCustomersChangedSince returns the customers created, updated or soft deleted from
+// since on, deleted ones included, in seq order. A zero since returns every customer.
+func (db *DatabaseConnection) GetCustomersChangedSince(since time.Time) (data []*models.Customer, err error) {
+	query := db.client.Unscoped().Model(&models.Customer{})
+	if !since.IsZero() {
+		query = query.Where("created_at >= ? OR updated_at >= ? OR deleted_at >= ?", since, since, since)
+	}
+	err = query.Order("seq").Find(&data).Error
+	return
+}
+
+// CountCustomers counts every customer row, soft deleted ones included
+func (db *DatabaseConnection) CountCustomers() (count int64, err error) {
+	err = db.client.Unscoped().Model(&models.Customer{}).Count(&count).Error
+	return
+}
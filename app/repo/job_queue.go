@@ -0,0 +1,36 @@
+package repo
+
+import (
+	"smart-contract-service/app/usecase"
+)
+
+// LocalJobQueue runs proof jobs in process on a fixed number of workers, jobs are
+// persisted before they are enqueued so the queue itself can be lost on restart
+type LocalJobQueue struct {
+	jobs    chan string
+	workers int
+}
+
+func NewLocalJobQueue(size int, workers int) usecase.JobQueue {
+	return &LocalJobQueue{jobs: make(chan string, size), workers: workers}
+}
+
+// Enqueue never blocks the caller, a full queue is reported instead
+func (q *LocalJobQueue) Enqueue(id string) (err error) {
+	select {
+	case q.jobs <- id:
+		return nil
+	default:
+		return usecase.ErrJobQueueFull
+	}
+}
+
+func (q *LocalJobQueue) Start(run func(id string)) {
+	for w := 0; w < q.workers; w++ {
+		go func() {
+			for id := range q.jobs {
+				run(id)
+			}
+		}()
+	}
+}
@@ -0,0 +1,330 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"smart-contract-service/app/usecase"
+	"smart-contract-service/configuration"
+	"smart-contract-service/internal"
+	models2 "smart-contract-service/models/circuit"
+	"sync"
+	"time"
+)
+
+type KeyStore struct {
+	cfg      configuration.ConfigApp
+	mu       sync.RWMutex
+	keys     map[string]*models2.Keys
+	modTime  map[string]time.Time
+	compiled map[string]constraint.ConstraintSystem
+	srs      map[ecc.ID]kzg.SRS
+	srsTime  map[ecc.ID]time.Time
+	ready    chan struct{}
+	once     sync.Once
+	// pending are the aggregators left to LoadAggregators, failed the ones it could not load
+	pending map[string]bool
+	failed  map[string]error
+}
+
+func NewKeyStore(cfg configuration.ConfigApp) *KeyStore {
+	return &KeyStore{
+		cfg:      cfg,
+		keys:     make(map[string]*models2.Keys),
+		modTime:  make(map[string]time.Time),
+		compiled: make(map[string]constraint.ConstraintSystem),
+		srs:      make(map[ecc.ID]kzg.SRS),
+		srsTime:  make(map[ecc.ID]time.Time),
+		ready:    make(chan struct{}),
+		pending:  make(map[string]bool),
+		failed:   make(map[string]error),
+	}
+}
+
+// storeKey indexes the keys of a circuit per backend
+func storeKey(algo string, b backend.ID) string {
+	return algo + "/" + b.String()
+}
+
+// Load reads the artifacts of every registered circuit and backend and marks the
+// store ready, it fails on the first circuit whose artifacts are missing, corrupt
+// or not matching the signed manifest. Aggregators are left to LoadAggregators.
+func (k *KeyStore) Load() error {
+	manifest, err := k.readManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, def := range models2.Definitions() {
+		for _, b := range def.Backends() {
+			if def.Aggregates != nil {
+				k.mu.Lock()
+				k.pending[storeKey(def.Name, b)] = true
+				k.mu.Unlock()
+				continue
+			}
+			if err = k.load(def, b, manifest); err != nil {
+				return err
+			}
+		}
+	}
+	k.once.Do(func() { close(k.ready) })
+	return nil
+}
+
+// LoadAggregators loads the aggregators left out by Load, a BW6-761 aggregator takes
+// minutes to read and compile so it runs once the store is ready. An aggregator is not
+// ready until then, one that fails to load reports its error from GetKeys.
+func (k *KeyStore) LoadAggregators() error {
+	manifest, manifestErr := k.readManifest()
+	var firstErr error
+	for _, def := range models2.Definitions() {
+		for _, b := range def.Backends() {
+			key := storeKey(def.Name, b)
+			k.mu.RLock()
+			pending := k.pending[key]
+			k.mu.RUnlock()
+			if !pending {
+				continue
+			}
+
+			err := manifestErr
+			if err == nil {
+				err = k.load(def, b, manifest)
+			}
+			k.mu.Lock()
+			delete(k.pending, key)
+			if err != nil {
+				k.failed[key] = err
+			}
+			k.mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Ready is closed once every registered circuit but the aggregators has been loaded
+func (k *KeyStore) Ready() <-chan struct{} {
+	return k.ready
+}
+
+func (k *KeyStore) IsReady() bool {
+	select {
+	case <-k.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k *KeyStore) GetKeys(algo string, b backend.ID) (*models2.Keys, error) {
+	if !k.IsReady() {
+		return nil, usecase.ErrKeysNotReady
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys, ok := k.keys[storeKey(algo, b)]
+	if ok {
+		return keys, nil
+	}
+	if k.pending[storeKey(algo, b)] {
+		return nil, fmt.Errorf("%w : %s (%s)", usecase.ErrKeysNotReady, algo, b)
+	}
+	if err, failed := k.failed[storeKey(algo, b)]; failed {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w : %s (%s)", models2.ErrAlgorithmNotFound, algo, b)
+}
+
+// Watch reloads a circuit whenever one of its artifacts changes on disk, it blocks forever
+func (k *KeyStore) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, def := range models2.Definitions() {
+			for _, b := range def.Backends() {
+				modTime, err := lastModified(def, b)
+				if err != nil {
+					continue
+				}
+
+				// aggregators still loading are read by LoadAggregators
+				k.mu.RLock()
+				loaded, pending := k.modTime[storeKey(def.Name, b)], k.pending[storeKey(def.Name, b)]
+				k.mu.RUnlock()
+				if pending || !modTime.After(loaded) {
+					continue
+				}
+
+				// the manifest is read again so a partially regenerated directory is rejected
+				manifest, err := k.readManifest()
+				if err == nil {
+					err = k.load(def, b, manifest)
+				}
+				if err != nil {
+					log.WithField("error", err).Errorf("Unable to reload circuit %s (%s)", def.Name, b)
+					continue
+				}
+				k.mu.Lock()
+				delete(k.failed, storeKey(def.Name, b))
+				k.mu.Unlock()
+				log.Infof("circuit %s (%s) reloaded", def.Name, b)
+			}
+		}
+	}
+}
+
+func (k *KeyStore) readManifest() (*models2.Manifest, error) {
+	manifest, err := models2.ReadManifest(internal.ManifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	publicKey, err := internal.GeneratePublicKey(k.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err = manifest.Verify(publicKey); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (k *KeyStore) load(def *models2.Definition, b backend.ID, manifest *models2.Manifest) error {
+	artifact := def.Artifact.For(b)
+	modTime, err := lastModified(def, b)
+	if err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+
+	entry, err := manifest.Entry(def.Name, b)
+	if err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if err = entry.Check(def, b); err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+
+	// read the constraint system, proving key and verifying keys
+	keys, err := models2.NewKeys(def.Curve, b)
+	if err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if err = internal.Deserialize(keys.Cs, artifact.R1cs); err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if err = internal.Deserialize(keys.Pk, artifact.Pk); err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if err = internal.Deserialize(keys.Vk, artifact.Vk); err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if b == backend.PLONK {
+		srs, err := k.readSRS(def.Curve)
+		if err != nil {
+			return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+		}
+		if err = keys.InitKZG(srs); err != nil {
+			return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+		}
+	}
+
+	// the keys must come from the circuit compiled into this binary
+	compiled, err := k.compile(def, b)
+	if err != nil {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: err}
+	}
+	if entry.Constraints != compiled.GetNbConstraints() || keys.Cs.GetNbConstraints() != compiled.GetNbConstraints() {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: fmt.Errorf("%d constraints compiled, %d in manifest", compiled.GetNbConstraints(), entry.Constraints)}
+	}
+	nbPublic := compiled.GetNbPublicVariables()
+	if b == backend.GROTH16 {
+		// the R1CS counts the constant wire as a public variable
+		nbPublic--
+	}
+	if keys.Vk.NbPublicWitness() != nbPublic {
+		return &usecase.ArtifactError{Circuit: def.Name, Err: fmt.Errorf("verifying key does not match the compiled circuit")}
+	}
+
+	k.mu.Lock()
+	k.keys[storeKey(def.Name, b)] = keys
+	k.modTime[storeKey(def.Name, b)] = modTime
+	k.mu.Unlock()
+	return nil
+}
+
+// readSRS returns the universal srs of the plonk circuits of the curve, read again when
+// the file changes
+func (k *KeyStore) readSRS(curve ecc.ID) (kzg.SRS, error) {
+	info, err := os.Stat(models2.SRSPath(curve))
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	srs, srsTime := k.srs[curve], k.srsTime[curve]
+	k.mu.RUnlock()
+	if srs != nil && !info.ModTime().After(srsTime) {
+		return srs, nil
+	}
+
+	if srs, err = models2.NewEmptySRS(curve); err != nil {
+		return nil, err
+	}
+	if err = internal.Deserialize(srs, models2.SRSPath(curve)); err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.srs[curve], k.srsTime[curve] = srs, info.ModTime()
+	k.mu.Unlock()
+	return srs, nil
+}
+
+// compile returns the constraint system of the circuit built into the binary
+func (k *KeyStore) compile(def *models2.Definition, b backend.ID) (constraint.ConstraintSystem, error) {
+	k.mu.RLock()
+	compiled, ok := k.compiled[storeKey(def.Name, b)]
+	k.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := models2.Compile(def.Curve, b, def.Circuit())
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.compiled[storeKey(def.Name, b)] = compiled
+	k.mu.Unlock()
+	return compiled, nil
+}
+
+// lastModified returns the most recent modification time of the circuit artifacts
+func lastModified(def *models2.Definition, b backend.ID) (modTime time.Time, err error) {
+	artifact := def.Artifact.For(b)
+	files := []string{artifact.R1cs, artifact.Pk, artifact.Vk}
+	if b == backend.PLONK {
+		files = append(files, models2.SRSPath(def.Curve))
+	}
+	for _, fileName := range files {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return modTime, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime, nil
+}
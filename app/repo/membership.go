@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"math/big"
+	"smart-contract-service/app/usecase"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"sync"
+	"time"
+)
+
+// MembershipTree keeps the merkle tree of customer commitments in memory. Every customer
+// row holds the leaf at its seq, deleted and not enrolled customers a zero one, so
+// instances syncing the same table build the same tree whatever order they see rows in.
+type MembershipTree struct {
+	mu      sync.RWMutex
+	curve   ecc.ID
+	depth   int
+	tree    *models2.MerkleTree
+	leaves  map[string]int
+	members int
+	// roots are the recent roots, oldest first, proofs made against them still verify
+	roots     []*big.Int
+	history   int
+	since     time.Time
+	updatedAt time.Time
+}
+
+func NewMembershipTree(history int) usecase.MembershipRepository {
+	return &MembershipTree{history: history}
+}
+
+func (m *MembershipTree) Rebuild(curve ecc.ID, depth int, customers []*models.Customer) (err error) {
+	tree, err := models2.NewMerkleTree(curve, depth)
+	if err != nil {
+		return err
+	}
+	rebuilt := &MembershipTree{curve: curve, depth: depth, tree: tree, leaves: make(map[string]int), history: m.history}
+	if err = rebuilt.apply(customers); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the roots published before stay valid until they age out
+	roots := append(m.roots, rebuilt.roots...)
+	m.curve, m.depth, m.tree, m.leaves, m.members = rebuilt.curve, rebuilt.depth, rebuilt.tree, rebuilt.leaves, rebuilt.members
+	m.since, m.updatedAt = rebuilt.since, rebuilt.updatedAt
+	m.roots = nil
+	for _, root := range roots {
+		m.pushRoot(root)
+	}
+	return nil
+}
+
+func (m *MembershipTree) Apply(customers []*models.Customer) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tree == nil {
+		return usecase.ErrMembershipNotReady
+	}
+	return m.apply(customers)
+}
+
+// apply sets the leaf of each customer, a customer seen again is only hashed when its
+// leaf changed so rows returned by overlapping syncs cost nothing
+func (m *MembershipTree) apply(customers []*models.Customer) error {
+	root := m.tree.Root()
+	for _, cData := range customers {
+		leaf := new(big.Int)
+		if !cData.DeletedAt.Valid && cData.Commitment != "" {
+			// a corrupt commitment cannot be proved, the customer is left out rather than
+			// stalling every later sync
+			if _, ok := leaf.SetString(cData.Commitment, 10); !ok {
+				leaf.SetInt64(0)
+			}
+		}
+
+		if cData.Seq <= 0 {
+			return fmt.Errorf("customer %s has no seq", cData.Id)
+		}
+		index := int(cData.Seq) - 1
+		_, ok := m.leaves[cData.Id]
+		// seqs of rows not committed yet, or rolled back, hold a zero leaf meanwhile
+		for m.tree.Len() < index {
+			if err := m.tree.Set(m.tree.Len(), new(big.Int)); err != nil {
+				return err
+			}
+		}
+		previous := m.tree.Leaf(index)
+		if ok && previous.Cmp(leaf) == 0 {
+			m.observe(cData)
+			continue
+		}
+		if err := m.tree.Set(index, leaf); err != nil {
+			return err
+		}
+		m.leaves[cData.Id] = index
+		if previous.Sign() != 0 {
+			m.members--
+		}
+		if leaf.Sign() != 0 {
+			m.members++
+		}
+		m.observe(cData)
+	}
+	// customers appended without a commitment leave the root as it was
+	if m.roots == nil || m.tree.Root().Cmp(root) != 0 {
+		m.pushRoot(m.tree.Root())
+		m.updatedAt = time.Now()
+	}
+	return nil
+}
+
+// observe moves the sync watermark to the latest change of the customer
+func (m *MembershipTree) observe(cData *models.Customer) {
+	for _, at := range []*time.Time{cData.CreatedAt, cData.UpdatedAt, &cData.DeletedAt.Time} {
+		if at != nil && at.After(m.since) {
+			m.since = *at
+		}
+	}
+}
+
+func (m *MembershipTree) pushRoot(root *big.Int) {
+	m.roots = append(m.roots, root)
+	if len(m.roots) > m.history+1 {
+		m.roots = m.roots[len(m.roots)-m.history-1:]
+	}
+}
+
+func (m *MembershipTree) Since() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.since
+}
+
+func (m *MembershipTree) Customers() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.leaves)
+}
+
+func (m *MembershipTree) Path(customerId string) (path *models2.MerklePath, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.tree == nil {
+		return nil, usecase.ErrMembershipNotReady
+	}
+	index, ok := m.leaves[customerId]
+	if !ok {
+		return nil, models2.ErrNotMember
+	}
+	return m.tree.Path(index)
+}
+
+func (m *MembershipTree) Root() (data *models.MembershipRoot, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.tree == nil {
+		return nil, usecase.ErrMembershipNotReady
+	}
+	return &models.MembershipRoot{
+		Circuit:   models2.MembershipAlgorithm,
+		Curve:     m.curve.String(),
+		Root:      m.tree.Root().String(),
+		Depth:     m.depth,
+		Leaves:    m.tree.Len(),
+		Members:   m.members,
+		UpdatedAt: m.updatedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (m *MembershipTree) IsRoot(root *big.Int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, known := range m.roots {
+		if known.Cmp(root) == 0 {
+			return true
+		}
+	}
+	return false
+}
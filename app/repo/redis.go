@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"github.com/redis/go-redis/v9"
 	"smart-contract-service/app/usecase"
 	"time"
@@ -15,12 +16,19 @@ func NewRedisConnection(client *redis.Client) usecase.RedisRepository {
 	return &RedisConnection{client: client}
 }
 
-func (r *RedisConnection) Set(key string, data string) (err error) {
-	err = r.client.Set(context.Background(), key, data, 5*time.Minute).Err()
+func (r *RedisConnection) Set(key string, data string, ttl time.Duration) (err error) {
+	err = r.client.Set(context.Background(), key, data, ttl).Err()
 	return err
 }
 
 func (r *RedisConnection) Get(key string) (val string, err error) {
 	val, err = r.client.Get(context.Background(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", usecase.ErrCacheMiss
+	}
 	return val, err
 }
+
+func (r *RedisConnection) Del(keys ...string) (err error) {
+	return r.client.Del(context.Background(), keys...).Err()
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrKeysNotReady       = errors.New("circuit keys are still loading")
+	ErrCustomerNotFound   = errors.New("customer not found")
+	ErrInvalidProofToken  = errors.New("invalid proof token")
+	ErrInvalidProof       = errors.New("invalid proof")
+	ErrArtifactNotFound   = errors.New("artifact not found")
+	ErrInvalidKey         = errors.New("invalid public key")
+	ErrInvalidBatch       = errors.New("invalid batch")
+	ErrJobNotFound        = errors.New("proof job not found")
+	ErrJobQueueFull       = errors.New("proof job queue is full")
+	ErrJobNotSupported    = errors.New("circuit cannot be proved in a job")
+	ErrProofSpent         = errors.New("proof already spent")
+	ErrCacheMiss          = errors.New("key not found")
+	ErrMembershipNotReady = errors.New("membership tree is not built yet")
+	ErrConsentMissing     = errors.New("customer did not consent to the payment intent")
+	ErrProofNotOwned      = errors.New("proof token was issued to another partner")
+	ErrNullifierMissing   = errors.New("proofs of the circuit derive no nullifier")
+)
+
+// reasons a proof token is not valid, they wrap the generic errors so callers not
+// telling them apart keep working
+var (
+	ErrProofTokenExpired   = fmt.Errorf("%w : expired or unknown", ErrInvalidProofToken)
+	ErrProofTokenCircuit   = fmt.Errorf("%w : issued for another circuit", ErrInvalidProofToken)
+	ErrProofTokenVersion   = fmt.Errorf("%w : unsupported version", ErrInvalidProofToken)
+	ErrProofRevoked        = fmt.Errorf("%w : revoked", ErrInvalidProofToken)
+	ErrPublicInputMismatch = fmt.Errorf("%w : public inputs do not match the customer", ErrInvalidProof)
+)
+
+// ArtifactError reports a circuit whose artifacts are missing or corrupt
+type ArtifactError struct {
+	Circuit string
+	Err     error
+}
+
+func (e *ArtifactError) Error() string {
+	return fmt.Sprintf("circuit %s: %s", e.Circuit, e.Err.Error())
+}
+
+func (e *ArtifactError) Unwrap() error {
+	return e.Err
+}
+
+// ProofError reports a failure while proving a witness
+type ProofError struct {
+	Circuit string
+	Err     error
+}
+
+func (e *ProofError) Error() string {
+	return fmt.Sprintf("prove %s: %s", e.Circuit, e.Err.Error())
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/signature"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"time"
+)
+
+// RegisterKey stores the EdDSA key of a customer. Without a public key in the request
+// the service generates the pair and keeps the private key encrypted, otherwise only
+// the public key is stored and proofs must come from the customer side.
+func (u *Usecase) RegisterKey(in *models.KeyRequest) (out *models.KeyResponse, err error) {
+	cData, err := u.db.GetCustomerData(in.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	if cData.Id == "" {
+		return nil, ErrCustomerNotFound
+	}
+
+	key := &models.CustomerKey{CustomerId: cData.Id}
+	if in.PublicKey != "" {
+		publicBin, err := hex.DecodeString(in.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidKey, err.Error())
+		}
+		publicKey, err := models2.ParsePublicKey(publicBin)
+		if err != nil {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidKey, err.Error())
+		}
+		key.PublicKey = hex.EncodeToString(publicKey.Bytes())
+	} else {
+		privateKey, err := models2.GenerateSigningKey()
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = hex.EncodeToString(privateKey.Public().Bytes())
+		key.PrivateKey, err = internal.EncryptAES(privateKey.Bytes(), u.cfg.KeyEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = u.db.SaveCustomerKey(key); err != nil {
+		return nil, err
+	}
+	return &models.KeyResponse{CustomerId: cData.Id, PublicKey: key.PublicKey}, nil
+}
+
+// EnrollCommitment commits to the customer KTP, account number and mother name with a
+// fresh salt, enrolling again after the attributes change replaces the commitment
+func (u *Usecase) EnrollCommitment(in *models.CommitmentRequest) (out *models.CommitmentResponse, err error) {
+	cData, err := u.db.GetCustomerData(in.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	if cData.Id == "" {
+		return nil, ErrCustomerNotFound
+	}
+
+	salt, err := models2.GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := models2.Commitment(models2.CommitmentCurve, cData, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.db.UpdateCustomerCommitment(cData.Id, commitment, salt); err != nil {
+		return nil, err
+	}
+	// the membership circuit can be proved with the new commitment right away
+	if err = u.SyncMembership(); err != nil {
+		log.WithField("error", err).Warn("Unable to sync the membership tree")
+	}
+	return &models.CommitmentResponse{CustomerId: cData.Id, Commitment: commitment, Salt: salt}, nil
+}
+
+// IssueCredential signs the birth date and branch of an enrolled customer, the
+// credential lets the customer side prove the credential circuit
+func (u *Usecase) IssueCredential(in *models.CredentialRequest) (out *models.Credential, err error) {
+	cData, err := u.db.GetCustomerData(in.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	if cData.Id == "" {
+		return nil, ErrCustomerNotFound
+	}
+
+	issuer, err := u.issuerKey()
+	if err != nil {
+		return nil, err
+	}
+	if issuer == nil {
+		return nil, models2.ErrIssuerKeyMissing
+	}
+	return models2.IssueCredential(cData, issuer)
+}
+
+// issuerKey reads the credential issuer key, nil when the service has none
+func (u *Usecase) issuerKey() (signature.Signer, error) {
+	issuer, err := models2.ReadIssuerKey(u.cfg.IssuerKeyLocation)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return issuer, err
+}
+
+// witnessInput collects the customer data and registered key used by the circuit
+func (u *Usecase) witnessInput(def *models2.Definition, cData *models.Customer) (*models2.WitnessInput, error) {
+	in := &models2.WitnessInput{
+		Curve:    def.Curve,
+		Customer: cData,
+		Policy: &models2.CredentialPolicy{
+			MinAge:   u.cfg.CredentialMinAge,
+			Branches: u.cfg.CredentialBranches,
+			Today:    time.Now(),
+		},
+	}
+
+	issuer, err := u.issuerKey()
+	if err != nil {
+		return nil, err
+	}
+	in.Issuer = issuer
+
+	key, err := u.db.GetCustomerKey(cData.Id)
+	if err != nil {
+		return nil, err
+	}
+	if key.PublicKey != "" {
+		publicBin, err := hex.DecodeString(key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if in.PublicKey, err = models2.ParsePublicKey(publicBin); err != nil {
+			return nil, err
+		}
+	}
+	if key.PrivateKey != "" {
+		privateBin, err := u.openSigningKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if in.SigningKey, err = models2.ParseSigningKey(privateBin); err != nil {
+			return nil, err
+		}
+	}
+
+	// the path is only known once the customer is in the membership tree
+	if path, err := u.members.Path(cData.Id); err == nil {
+		in.Membership = path
+	}
+	in.KnownRoot = u.members.IsRoot
+	return in, nil
+}
+
+// openSigningKey decrypts a private key the service holds, a key sealed with the token
+// secret before the key encryption key was set is sealed again with it
+func (u *Usecase) openSigningKey(key *models.CustomerKey) ([]byte, error) {
+	privateBin, err := internal.DecryptAES(key.PrivateKey, u.cfg.KeyEncryptionKey)
+	if err == nil {
+		return privateBin, nil
+	}
+	privateBin, legacyErr := internal.DecryptAES(key.PrivateKey, u.cfg.Secret)
+	if legacyErr != nil {
+		return nil, err
+	}
+	resealed, err := internal.EncryptAES(privateBin, u.cfg.KeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = u.db.SaveCustomerKey(&models.CustomerKey{CustomerId: key.CustomerId, PublicKey: key.PublicKey, PrivateKey: resealed}); err != nil {
+		return nil, err
+	}
+	log.WithField("customer", key.CustomerId).Info("signing key sealed again with the key encryption key")
+	return privateBin, nil
+}
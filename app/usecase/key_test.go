@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"encoding/hex"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+func TestRegisterKeySealsWithKeyEncryptionKey(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	if _, err := u.RegisterKey(&models.KeyRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	sealed := db.keys["cust-1"].PrivateKey
+	if _, err := internal.DecryptAES(sealed, u.cfg.Secret); err == nil {
+		t.Fatal("signing key sealed with the token secret")
+	}
+	if _, err := internal.DecryptAES(sealed, u.cfg.KeyEncryptionKey); err != nil {
+		t.Fatalf("signing key not sealed with the key encryption key: %v", err)
+	}
+}
+
+func TestSigningKeySealedWithSecret(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	key, err := models2.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := internal.EncryptAES(key.Bytes(), u.cfg.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.keys["cust-1"] = &models.CustomerKey{CustomerId: "cust-1", PublicKey: hex.EncodeToString(key.Public().Bytes()), PrivateKey: sealed}
+
+	if _, err = u.GetProof(models2.EddsaAlgorithm, "cust-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = internal.DecryptAES(db.keys["cust-1"].PrivateKey, u.cfg.KeyEncryptionKey); err != nil {
+		t.Fatalf("signing key not sealed again: %v", err)
+	}
+
+	if db.keys["cust-1"].PrivateKey, err = internal.EncryptAES(key.Bytes(), "another-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.GetProof(models2.EddsaAlgorithm, "cust-1", ""); err == nil {
+		t.Fatal("proved with a signing key sealed with neither key")
+	}
+}
+
+func TestEddsaProofSignsCommitment(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	if _, err := u.RegisterKey(&models.KeyRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := u.GetProof(models2.EddsaAlgorithm, "cust-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := u.VerifyProof(models2.EddsaAlgorithm, data.Hash)
+	if err != nil || !result.Valid {
+		t.Fatalf("proof not verified: %+v %v", result, err)
+	}
+
+	// the signed commitment is replaced by enrolling again
+	if _, err = u.EnrollCommitment(&models.CommitmentRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = u.VerifyProof(models2.EddsaAlgorithm, data.Hash); err != nil || result.Valid {
+		t.Fatalf("proof of a replaced commitment verified: %+v %v", result, err)
+	}
+}
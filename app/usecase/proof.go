@@ -0,0 +1,522 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"strings"
+	"time"
+)
+
+// GetProof proves the circuit for the customer, context is what the proof is made for
+// and binds its nullifier, a random nonce when empty
+func (u *Usecase) GetProof(algo string, id string, context string) (data *models.ProofResponse, err error) {
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := u.keys.GetKeys(def.Name, def.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return u.prove(def, keys, id, context, nil, "")
+}
+
+// GetPaymentProof proves the payment circuit of the request for the customer, the proof
+// only pays the intent and only when the customer signed it
+func (u *Usecase) GetPaymentProof(in *models.PaymentProofRequest) (data *models.ProofResponse, err error) {
+	algo := in.Algo
+	if algo == "" {
+		algo = models2.PaymentAlgorithm
+	}
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return nil, err
+	}
+	if def.CheckIntent == nil {
+		return nil, fmt.Errorf("%w : %s", models2.ErrPaymentNotSupported, def.Name)
+	}
+
+	intent := &models.PaymentIntent{
+		PartnerReferenceNo: in.PartnerReferenceNo,
+		Amount:             in.Amount.Value,
+		Currency:           in.Amount.Currency,
+		ExternalId:         in.ExternalId,
+	}
+	if err = u.checkConsent(in.CustomerId, intent, in.Consent); err != nil {
+		return nil, err
+	}
+
+	keys, err := u.keys.GetKeys(def.Name, def.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return u.prove(def, keys, in.CustomerId, "", intent, in.PartnerId)
+}
+
+// checkConsent asserts the customer signed the intent with a key it holds, the service
+// keeps the private half of generated keys and could sign with them on its own
+func (u *Usecase) checkConsent(customerId string, intent *models.PaymentIntent, consent string) error {
+	key, err := u.db.GetCustomerKey(customerId)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.PublicKey == "" || key.PrivateKey != "" {
+		return fmt.Errorf("%w : customer holds no key of its own", ErrConsentMissing)
+	}
+	if consent == "" {
+		return ErrConsentMissing
+	}
+	publicBin, err := hex.DecodeString(key.PublicKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := models2.ParsePublicKey(publicBin)
+	if err != nil {
+		return err
+	}
+	if err = models2.VerifyConsent(publicKey, intent, consent); err != nil {
+		return fmt.Errorf("%w : %s", ErrConsentMissing, err.Error())
+	}
+	return nil
+}
+
+// GetRangeProof proves the amount of the payment is in the partner range and covered by
+// the customer balance, neither the balance nor the amount are public in the proof. The
+// customer must sign the intent like for a payment proof, the answer tells about its balance.
+func (u *Usecase) GetRangeProof(in *models.PaymentProofRequest) (data *models.ProofResponse, err error) {
+	def, err := models2.Lookup(models2.RangeAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	intent := &models.PaymentIntent{
+		PartnerReferenceNo: in.PartnerReferenceNo,
+		Amount:             in.Amount.Value,
+		Currency:           in.Amount.Currency,
+		ExternalId:         in.ExternalId,
+	}
+	if err = u.checkConsent(in.CustomerId, intent, in.Consent); err != nil {
+		return nil, err
+	}
+
+	keys, err := u.keys.GetKeys(def.Name, def.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return u.prove(def, keys, in.CustomerId, "", intent, in.PartnerId)
+}
+
+// prove builds the witness of the customer, proves it and issues the proof token to the partner
+func (u *Usecase) prove(def *models2.Definition, keys *models2.Keys, id string, context string, intent *models.PaymentIntent, partnerId string) (data *models.ProofResponse, err error) {
+	cData, err := u.db.GetCustomerData(id)
+	if err != nil {
+		return nil, err
+	}
+	if cData.Id == "" {
+		return nil, ErrCustomerNotFound
+	}
+
+	in, err := u.witnessInput(def, cData)
+	if err != nil {
+		return nil, err
+	}
+	in.Context = context
+	if err = u.setIntent(in, intent); err != nil {
+		return nil, err
+	}
+
+	assignment, err := def.Assign(in)
+	if err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+
+	witness, err := frontend.NewWitness(assignment, def.Curve.ScalarField())
+	if err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+
+	proof, err := keys.Prove(witness)
+	if err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+
+	var proofBuf bytes.Buffer
+	if _, err = proof.WriteTo(&proofBuf); err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+	dataBin, err := publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+
+	token, expiresAt, err := u.issueProofToken(def.Name, keys.Curve, keys.Backend, tokenSubject(def, cData), partnerId, proofBuf.Bytes(), dataBin)
+	if err != nil {
+		return nil, err
+	}
+	data = &models.ProofResponse{Hash: token, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)}
+	if data.Public, err = models2.EncodePublic(def, publicWitness); err != nil {
+		return nil, &ProofError{Circuit: def.Name, Err: err}
+	}
+	if def.Nullifier != nil {
+		if data.Nullifier, err = def.Nullifier(publicWitness); err != nil {
+			return nil, &ProofError{Circuit: def.Name, Err: err}
+		}
+	}
+
+	return
+}
+
+// VerifyProof verifies a proof token, a proof that is not valid is reported in the result
+// with the reason, the error is only set when the proof could not be checked at all
+func (u *Usecase) VerifyProof(algo string, code string) (*models.VerificationResult, error) {
+	result, err := u.verificationResult(u.lookups(), algo, code)
+	switch result.Reason {
+	case models.ReasonUnknownAlgorithm, models.ReasonUnavailable:
+		return nil, err
+	}
+	return result, nil
+}
+
+// verificationResult verifies a proof token and describes the outcome, err is the reason
+// the proof is not valid
+func (u *Usecase) verificationResult(l *proofLookups, algo string, code string) (*models.VerificationResult, error) {
+	start := time.Now()
+	token, err := u.verifyToken(l, algo, code)
+	result := &models.VerificationResult{
+		Circuit:    algo,
+		VerifiedAt: start.UTC().Format(time.RFC3339),
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Reason, result.Message = verificationReason(err), err.Error()
+		return result, err
+	}
+
+	result.Backend, result.Curve, result.CustomerId = token.backend.String(), token.curve.String(), token.customerId
+	def, err := models2.Lookup(algo)
+	if err == nil {
+		result.Public, err = models2.EncodePublic(def, token.publicWitness)
+	}
+	if err != nil {
+		result.Reason, result.Message = models.ReasonMalformedToken, err.Error()
+		return result, err
+	}
+	result.Valid = true
+	return result, nil
+}
+
+// verificationReason maps the error of verifyProof to a reason code, the most specific first
+func verificationReason(err error) string {
+	switch {
+	case errors.Is(err, models2.ErrAlgorithmNotFound):
+		return models.ReasonUnknownAlgorithm
+	case errors.Is(err, ErrProofTokenExpired):
+		return models.ReasonExpired
+	case errors.Is(err, ErrProofRevoked):
+		return models.ReasonRevoked
+	case errors.Is(err, ErrProofTokenCircuit), errors.Is(err, models2.ErrCurveNotSupported),
+		errors.Is(err, models2.ErrBackendNotSupported):
+		return models.ReasonCircuitMismatch
+	case errors.Is(err, ErrInvalidProofToken):
+		return models.ReasonMalformedToken
+	case errors.Is(err, ErrCustomerNotFound):
+		return models.ReasonCustomerNotFound
+	case errors.Is(err, ErrPublicInputMismatch):
+		return models.ReasonPublicInputMismatch
+	case errors.Is(err, ErrInvalidProof):
+		return models.ReasonInvalidProof
+	default:
+		// keys still loading, database or redis down
+		return models.ReasonUnavailable
+	}
+}
+
+// proofLookups are what verifying a token reads besides the token and its keys
+type proofLookups struct {
+	publicKey func() (*rsa.PublicKey, error)
+	customer  func(id string) (*models.Customer, error)
+}
+
+// lookups reads everything afresh for each token
+func (u *Usecase) lookups() *proofLookups {
+	return &proofLookups{publicKey: u.publicKey, customer: u.db.GetCustomerData}
+}
+
+// verifyProof returns the proof token once verified for its customer, or why it is not valid
+func (u *Usecase) verifyProof(algo string, code string) (*proofToken, error) {
+	return u.verifyToken(u.lookups(), algo, code)
+}
+
+// verifyToken verifies a proof token like verifyProof, reading through l
+func (u *Usecase) verifyToken(l *proofLookups, algo string, code string) (*proofToken, error) {
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := u.resolveProofToken(l.publicKey, def.Name, code)
+	if err != nil {
+		return nil, err
+	}
+	if err = u.checkRevoked(def, token); err != nil {
+		return nil, err
+	}
+
+	// tokens of anonymous circuits issued before they were anonymous still name the customer
+	var cData *models.Customer
+	if def.Anonymous {
+		token.customerId = ""
+	} else {
+		if cData, err = l.customer(token.customerId); err != nil {
+			return nil, err
+		}
+		if cData.Id == "" {
+			return nil, ErrCustomerNotFound
+		}
+	}
+
+	// tokens carry the backend they were proved with, legacy backends stay verifiable
+	if token.curve != def.Curve {
+		return nil, fmt.Errorf("%w : %s for %s", models2.ErrCurveNotSupported, token.curve, def.Name)
+	}
+	if !def.Accepts(token.backend) {
+		return nil, fmt.Errorf("%w : %s for %s", models2.ErrBackendNotSupported, token.backend, def.Name)
+	}
+	keys, err := u.keys.GetKeys(def.Name, token.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify the proof using witness
+	err = keys.Verify(token.proof, token.publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	if err = u.checkPublic(def, cData, token.publicWitness); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrPublicInputMismatch, err.Error())
+	}
+	return token, nil
+}
+
+// checkPublic binds a verified public witness to what is registered for the customer,
+// anonymous proofs to what the service publishes
+func (u *Usecase) checkPublic(def *models2.Definition, cData *models.Customer, publicWitness witness.Witness) error {
+	if def.CheckPublic == nil {
+		return nil
+	}
+	if def.Anonymous {
+		return def.CheckPublic(publicWitness, &models2.WitnessInput{Curve: def.Curve, KnownRoot: u.members.IsRoot})
+	}
+	in, err := u.witnessInput(def, cData)
+	if err != nil {
+		return err
+	}
+	return def.CheckPublic(publicWitness, in)
+}
+
+func (u *Usecase) GetProofCalldata(code string) (data *models.ProofCalldata, err error) {
+	token, err := u.readProofToken("", code)
+	if err != nil {
+		return nil, err
+	}
+	// the verifier contracts are only exported for BN254
+	if token.curve != ecc.BN254 {
+		return nil, fmt.Errorf("%w : calldata is only available for BN254 proofs", models2.ErrCurveNotSupported)
+	}
+	// the plonk verifier contract takes a different proof layout
+	proof, ok := token.proof.(groth16.Proof)
+	if !ok {
+		return nil, fmt.Errorf("%w : calldata is only available for groth16 proofs", models2.ErrBackendNotSupported)
+	}
+	return internal.ProofCalldata(proof, token.publicWitness)
+}
+
+func (u *Usecase) IsReady() bool {
+	return u.keys.IsReady()
+}
+
+// VerifyExternalProof checks a proof produced by the partner's own prover and binds it
+// to the customer by issuing a proof token, the secret witness never reaches the service
+func (u *Usecase) VerifyExternalProof(in *models.ExternalProofRequest) (data *models.ProofResponse, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := models2.ParseBackend(in.Backend)
+	if err != nil {
+		return nil, err
+	}
+	if !def.Accepts(b) {
+		return nil, fmt.Errorf("%w : %s for %s", models2.ErrBackendNotSupported, b, def.Name)
+	}
+	curve, err := models2.ParseCurve(in.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if curve != def.Curve {
+		return nil, fmt.Errorf("%w : %s for %s", models2.ErrCurveNotSupported, curve, def.Name)
+	}
+	keys, err := u.keys.GetKeys(def.Name, b)
+	if err != nil {
+		return nil, err
+	}
+
+	// anonymous proofs are taken without a customer, whichever id the partner sends
+	var cData *models.Customer
+	if !def.Anonymous {
+		if cData, err = u.db.GetCustomerData(in.CustomerId); err != nil {
+			return nil, err
+		}
+		if cData.Id == "" {
+			return nil, ErrCustomerNotFound
+		}
+	}
+
+	proofBin, err := base64.StdEncoding.DecodeString(in.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	publicBin, err := externalPublicWitness(def, in)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &proofToken{circuit: def.Name, curve: curve, backend: b, customerId: tokenSubject(def, cData)}
+	if err = token.decode(proofBin, publicBin); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+
+	// verify the proof using witness
+	if err = keys.Verify(token.proof, token.publicWitness); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	if err = u.checkPublic(def, cData, token.publicWitness); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrPublicInputMismatch, err.Error())
+	}
+	// a revoked proof does not come back as a fresh token
+	if err = u.checkRevoked(def, token); err != nil {
+		return nil, err
+	}
+
+	hash, expiresAt, err := u.issueProofToken(def.Name, curve, b, token.customerId, in.PartnerId, proofBin, publicBin)
+	if err != nil {
+		return nil, err
+	}
+	data = &models.ProofResponse{Hash: hash, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)}
+	if data.Public, err = models2.EncodePublic(def, token.publicWitness); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// externalPublicWitness returns the binary public witness of an external proof, given
+// either as the gnark binary or as decimal inputs by name
+func externalPublicWitness(def *models2.Definition, in *models.ExternalProofRequest) ([]byte, error) {
+	if in.PublicWitness != "" || in.Public == nil {
+		publicBin, err := base64.StdEncoding.DecodeString(in.PublicWitness)
+		if err != nil {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+		}
+		return publicBin, nil
+	}
+	publicWitness, err := models2.DecodePublic(def, in.Public)
+	if err != nil {
+		return nil, err
+	}
+	return publicWitness.MarshalBinary()
+}
+
+// GetPublicSchema returns the public inputs of a circuit, so proofs can be inspected and
+// verified without gnark
+func (u *Usecase) GetPublicSchema(algo string) (data *models.PublicSchema, err error) {
+	def, err := lookupPublished(algo)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := models2.PublicSchema(def)
+	if err != nil {
+		return nil, err
+	}
+	data = &models.PublicSchema{
+		Circuit: def.Name,
+		Curve:   def.Curve.String(),
+		Field:   def.Curve.ScalarField().String(),
+		Inputs:  make([]models.PublicInput, len(inputs)),
+	}
+	for i, input := range inputs {
+		data.Inputs[i] = models.PublicInput{Name: input.Name, Type: input.Type, Description: input.Description}
+	}
+	return data, nil
+}
+
+// lookupPublished returns the circuit whose artifacts are published under the algorithm
+// name, aggregators publish theirs under the name of the circuit they fold
+func lookupPublished(algo string) (*models2.Definition, error) {
+	def, err := models2.Lookup(algo)
+	if errors.Is(err, models2.ErrAlgorithmNotFound) && strings.HasSuffix(algo, models2.AggregateSuffix) {
+		if agg, aggErr := models2.LookupAggregator(strings.TrimSuffix(algo, models2.AggregateSuffix)); aggErr == nil {
+			return agg, nil
+		}
+	}
+	return def, err
+}
+
+// GetCircuitArtifact returns the location of a published circuit artifact, the
+// backend defaults to the one the circuit proves with
+func (u *Usecase) GetCircuitArtifact(algo string, artifact string, backendName string) (fileName string, err error) {
+	def, err := lookupPublished(algo)
+	if err != nil {
+		return "", err
+	}
+	b := def.Backend
+	if backendName != "" {
+		if b, err = models2.ParseBackend(backendName); err != nil {
+			return "", err
+		}
+	}
+	if !def.Accepts(b) {
+		return "", fmt.Errorf("%w : %s for %s", ErrArtifactNotFound, b, def.Name)
+	}
+
+	files := def.Artifact.For(b)
+	switch artifact {
+	case "r1cs":
+		return files.R1cs, nil
+	case "pk":
+		return files.Pk, nil
+	case "vk":
+		return files.Vk, nil
+	case "sol":
+		// gnark only exports verifier contracts for BN254
+		if def.Curve != ecc.BN254 {
+			return "", fmt.Errorf("%w : %s on %s", ErrArtifactNotFound, artifact, def.Curve)
+		}
+		return files.Solidity, nil
+	case "srs":
+		if b != backend.PLONK {
+			return "", fmt.Errorf("%w : %s", ErrArtifactNotFound, artifact)
+		}
+		return models2.SRSPath(def.Curve), nil
+	default:
+		return "", fmt.Errorf("%w : %s", ErrArtifactNotFound, artifact)
+	}
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+)
+
+// AggregateProof folds the proof tokens of a circuit into one proof of its aggregator, so
+// a settlement window is archived or posted as a single proof. Every token is verified
+// first, one invalid token fails the aggregation.
+func (u *Usecase) AggregateProof(in *models.AggregateProofRequest) (data *models.AggregateProofResponse, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+	agg, err := models2.LookupAggregator(def.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.Proofs) == 0 {
+		return nil, fmt.Errorf("%w : no proof", ErrInvalidBatch)
+	}
+	if len(in.Proofs) > agg.Aggregate {
+		return nil, fmt.Errorf("%w : %d proofs, at most %d", ErrInvalidBatch, len(in.Proofs), agg.Aggregate)
+	}
+
+	keys, err := u.keys.GetKeys(def.Name, backend.GROTH16)
+	if err != nil {
+		return nil, err
+	}
+	aggKeys, err := u.keys.GetKeys(agg.Name, agg.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	proofs := make([]models2.Proof, len(in.Proofs))
+	publicWitnesses := make([]witness.Witness, len(in.Proofs))
+	errs := make([]error, len(in.Proofs))
+	parallel(len(in.Proofs), func(i int) {
+		token, err := u.verifyProof(def.Name, in.Proofs[i])
+		if err == nil && token.backend != backend.GROTH16 {
+			err = fmt.Errorf("%w : %s proofs cannot be aggregated", models2.ErrBackendNotSupported, token.backend)
+		}
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		proofs[i], publicWitnesses[i] = token.proof, token.publicWitness
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("proof %d: %w", i, err)
+		}
+	}
+
+	assignment, err := models2.AssignAggregate(agg, keys.Vk, proofs, publicWitnesses)
+	if err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+	fullWitness, err := frontend.NewWitness(assignment, agg.Curve.ScalarField())
+	if err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+	proof, err := aggKeys.Prove(fullWitness)
+	if err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+
+	var proofBuf bytes.Buffer
+	if _, err = proof.WriteTo(&proofBuf); err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+	publicBin, err := publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, &ProofError{Circuit: agg.Name, Err: err}
+	}
+
+	return &models.AggregateProofResponse{
+		Algo:          def.Name,
+		Aggregator:    agg.Name,
+		Curve:         agg.Curve.String(),
+		Count:         len(in.Proofs),
+		Size:          agg.Aggregate,
+		Proof:         base64.StdEncoding.EncodeToString(proofBuf.Bytes()),
+		PublicWitness: base64.StdEncoding.EncodeToString(publicBin),
+	}, nil
+}
+
+// VerifyAggregateProof verifies an aggregated proof against the current keys of the circuit
+// and returns the public inputs of the proofs it folds, without the padding
+func (u *Usecase) VerifyAggregateProof(in *models.AggregateVerifyRequest) (data *models.AggregateVerifyResponse, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+	agg, err := models2.LookupAggregator(def.Name)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := u.keys.GetKeys(def.Name, backend.GROTH16)
+	if err != nil {
+		return nil, err
+	}
+	aggKeys, err := u.keys.GetKeys(agg.Name, agg.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	proofBin, err := base64.StdEncoding.DecodeString(in.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	publicBin, err := base64.StdEncoding.DecodeString(in.PublicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	token := &proofToken{circuit: agg.Name, curve: agg.Curve, backend: agg.Backend}
+	if err = token.decode(proofBin, publicBin); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+
+	if err = aggKeys.Verify(token.proof, token.publicWitness); err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	public, err := models2.CheckAggregatePublic(agg, keys.Vk, token.publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+
+	data = &models.AggregateVerifyResponse{Algo: def.Name, Count: len(public), Size: agg.Aggregate, Public: make([][]string, len(public))}
+	for i := range public {
+		for _, v := range public[i] {
+			data.Public[i] = append(data.Public[i], v.String())
+		}
+	}
+	return data, nil
+}
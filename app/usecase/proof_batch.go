@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"runtime"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"sync"
+)
+
+// GetBatchProof proves the circuit for every customer of the batch on a worker pool sized
+// to the cpus, a customer that cannot be proved is reported without failing the batch
+func (u *Usecase) GetBatchProof(in *models.BatchProofRequest) (data *models.BatchProofResponse, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+	if len(in.CustomerIds) == 0 {
+		return nil, fmt.Errorf("%w : no customer id", ErrInvalidBatch)
+	}
+	if len(in.CustomerIds) > u.cfg.BatchProofMaxSize {
+		return nil, fmt.Errorf("%w : %d customer ids, at most %d", ErrInvalidBatch, len(in.CustomerIds), u.cfg.BatchProofMaxSize)
+	}
+
+	keys, err := u.keys.GetKeys(def.Name, def.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	data = &models.BatchProofResponse{
+		Algo:    def.Name,
+		Results: make([]models.BatchProofResult, len(in.CustomerIds)),
+	}
+	parallel(len(in.CustomerIds), func(i int) {
+		// each call writes its own item, no lock needed
+		result := &data.Results[i]
+		result.CustomerId = in.CustomerIds[i]
+		proof, err := u.prove(def, keys, result.CustomerId, "", nil, in.PartnerId)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		result.Hash = proof.Hash
+	})
+
+	for _, result := range data.Results {
+		if result.Error != "" {
+			data.Failed++
+		} else {
+			data.Proved++
+		}
+	}
+	return data, nil
+}
+
+// VerifyBatchProof verifies every proof token of the batch in parallel, the results are
+// in the order of the request and give the reason code of each invalid proof. The service
+// key and the customers are read once for the batch and a token sent twice is verified
+// once, so only the proofs themselves are verified one by one.
+func (u *Usecase) VerifyBatchProof(in *models.BatchVerifyRequest) (data *models.BatchVerifyResponse, err error) {
+	if len(in.Proofs) == 0 {
+		return nil, fmt.Errorf("%w : no proof", ErrInvalidBatch)
+	}
+	if len(in.Proofs) > u.cfg.BatchVerifyMaxSize {
+		return nil, fmt.Errorf("%w : %d proofs, at most %d", ErrInvalidBatch, len(in.Proofs), u.cfg.BatchVerifyMaxSize)
+	}
+
+	// the first request of each token is verified, the others copy its result
+	first := make(map[models.ProofRequest]int, len(in.Proofs))
+	var distinct []int
+	for i, proof := range in.Proofs {
+		if _, ok := first[proof]; !ok {
+			first[proof] = i
+			distinct = append(distinct, i)
+		}
+	}
+
+	lookups := u.batchLookups()
+	data = &models.BatchVerifyResponse{Results: make([]models.VerificationResult, len(in.Proofs))}
+	parallel(len(distinct), func(k int) {
+		// every outcome is a result, including the proofs that could not be checked
+		i := distinct[k]
+		result, _ := u.verificationResult(lookups, in.Proofs[i].Algo, in.Proofs[i].Proof)
+		data.Results[i] = *result
+	})
+	for i, proof := range in.Proofs {
+		data.Results[i] = data.Results[first[proof]]
+	}
+
+	for _, result := range data.Results {
+		if result.Valid {
+			data.Valid++
+		} else {
+			data.Invalid++
+		}
+	}
+	return data, nil
+}
+
+// batchLookups reads the service key and each customer once, whatever the number of
+// tokens verified through it and of the goroutines verifying them
+func (u *Usecase) batchLookups() *proofLookups {
+	var (
+		keyOnce   sync.Once
+		key       *rsa.PublicKey
+		keyErr    error
+		mu        sync.Mutex
+		customers = make(map[string]*customerLookup)
+	)
+	return &proofLookups{
+		publicKey: func() (*rsa.PublicKey, error) {
+			keyOnce.Do(func() { key, keyErr = u.publicKey() })
+			return key, keyErr
+		},
+		customer: func(id string) (*models.Customer, error) {
+			mu.Lock()
+			lookup, ok := customers[id]
+			if !ok {
+				lookup = &customerLookup{}
+				customers[id] = lookup
+			}
+			mu.Unlock()
+			lookup.once.Do(func() { lookup.data, lookup.err = u.db.GetCustomerData(id) })
+			return lookup.data, lookup.err
+		},
+	}
+}
+
+// customerLookup is a customer read once for a batch
+type customerLookup struct {
+	once sync.Once
+	data *models.Customer
+	err  error
+}
+
+// parallel calls work for each index on a worker pool sized to the cpus
+func parallel(n int, work func(i int)) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"crypto/rsa"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyBatchProofReadsOnce(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	u.cfg.ProofTokenMode = ProofTokenStateless
+	signed, err := u.GetProof(models2.HashAlgorithm, "cust-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.cfg.ProofTokenMode = ProofTokenRedis
+	handle, err := u.GetProof(models2.HashAlgorithm, "cust-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keyReads int32
+	publicKey := u.publicKey
+	u.publicKey = func() (*rsa.PublicKey, error) {
+		atomic.AddInt32(&keyReads, 1)
+		return publicKey()
+	}
+	db.customerReads = 0
+
+	in := &models.BatchVerifyRequest{}
+	for i := 0; i < 4; i++ {
+		in.Proofs = append(in.Proofs,
+			models.ProofRequest{Algo: models2.HashAlgorithm, Proof: signed.Hash},
+			models.ProofRequest{Algo: models2.HashAlgorithm, Proof: handle.Hash})
+	}
+	in.Proofs = append(in.Proofs, models.ProofRequest{Algo: models2.HashAlgorithm, Proof: "bm90IGEgdG9rZW4="})
+	data, err := u.VerifyBatchProof(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Valid != 8 || data.Invalid != 1 || data.Results[8].Reason != models.ReasonMalformedToken {
+		t.Fatalf("%d valid and %d invalid proofs, last one %s", data.Valid, data.Invalid, data.Results[8].Reason)
+	}
+
+	// the signature of a token sent four times is checked once, with the key read once
+	if keyReads != 1 {
+		t.Fatalf("service key read %d times for one signed token", keyReads)
+	}
+	if db.customerReads != 1 {
+		t.Fatalf("customer read %d times for one customer", db.customerReads)
+	}
+}
+
+func TestGetBatchProof(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	_, err := u.GetBatchProof(&models.BatchProofRequest{Algo: "sha1", CustomerIds: []string{"cust-1"}})
+	assertIs(t, err, models2.ErrAlgorithmNotFound)
+	_, err = u.GetBatchProof(&models.BatchProofRequest{Algo: models2.HashAlgorithm})
+	assertIs(t, err, ErrInvalidBatch)
+	tooMany := &models.BatchProofRequest{Algo: models2.HashAlgorithm}
+	for i := 0; i <= u.cfg.BatchProofMaxSize; i++ {
+		tooMany.CustomerIds = append(tooMany.CustomerIds, "cust-1")
+	}
+	_, err = u.GetBatchProof(tooMany)
+	assertIs(t, err, ErrInvalidBatch)
+
+	// a customer that cannot be proved fails its item, not the batch
+	data, err := u.GetBatchProof(&models.BatchProofRequest{Algo: models2.HashAlgorithm, CustomerIds: []string{"cust-1", "cust-2"}, PartnerId: "partner-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Proved != 1 || data.Failed != 1 {
+		t.Fatalf("%d proved and %d failed, expected one of each", data.Proved, data.Failed)
+	}
+	if data.Results[0].Hash == "" || data.Results[1].CustomerId != "cust-2" || data.Results[1].Error == "" {
+		t.Fatalf("results %+v", data.Results)
+	}
+	if result, err := u.VerifyProof(models2.HashAlgorithm, data.Results[0].Hash); err != nil || !result.Valid {
+		t.Fatalf("batch proof not verified: %+v %v", result, err)
+	}
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+// externalProof returns a hash proof of the customer as a partner would submit it
+func externalProof(t *testing.T, u *Usecase) *models.ExternalProofRequest {
+	t.Helper()
+	data, err := u.GetProof(models2.HashAlgorithm, "cust-1", "ref-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := u.readProofToken(models2.HashAlgorithm, data.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proof bytes.Buffer
+	if _, err = token.proof.WriteTo(&proof); err != nil {
+		t.Fatal(err)
+	}
+	return &models.ExternalProofRequest{
+		Algo:       models2.HashAlgorithm,
+		CustomerId: "cust-1",
+		Proof:      base64.StdEncoding.EncodeToString(proof.Bytes()),
+		Public:     data.Public,
+		PartnerId:  "partner-1",
+	}
+}
+
+func TestVerifyExternalProof(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	in := externalProof(t, u)
+	data, err := u.VerifyExternalProof(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := u.VerifyProof(models2.HashAlgorithm, data.Hash); err != nil || !result.Valid {
+		t.Fatalf("issued token not verified: %+v %v", result, err)
+	}
+
+	for name, c := range map[string]struct {
+		change func(in *models.ExternalProofRequest)
+		err    error
+	}{
+		"unknown algorithm": {func(in *models.ExternalProofRequest) { in.Algo = "sha1" }, models2.ErrAlgorithmNotFound},
+		"unknown backend":   {func(in *models.ExternalProofRequest) { in.Backend = "stark" }, models2.ErrBackendNotSupported},
+		"other curve":       {func(in *models.ExternalProofRequest) { in.Curve = "bls12_381" }, models2.ErrCurveNotSupported},
+		"unknown customer":  {func(in *models.ExternalProofRequest) { in.CustomerId = "cust-2" }, ErrCustomerNotFound},
+		"proof not base64":  {func(in *models.ExternalProofRequest) { in.Proof = "not base64!" }, ErrInvalidProof},
+		"missing input": {func(in *models.ExternalProofRequest) {
+			in.Public = map[string]string{"Hash": in.Public["Hash"], "Context": in.Public["Context"]}
+		}, models2.ErrInvalidPublicInput},
+		"input not a field element": {func(in *models.ExternalProofRequest) {
+			in.Public = map[string]string{"Hash": in.Public["Hash"], "Context": "0x01", "Nullifier": in.Public["Nullifier"]}
+		}, models2.ErrInvalidPublicInput},
+		"unknown input": {func(in *models.ExternalProofRequest) {
+			in.Public = map[string]string{"Hash": in.Public["Hash"], "Context": in.Public["Context"], "Nullifier": in.Public["Nullifier"], "Amount": "1"}
+		}, models2.ErrInvalidPublicInput},
+		"other context": {func(in *models.ExternalProofRequest) {
+			in.Public = map[string]string{"Hash": in.Public["Hash"], "Context": "1", "Nullifier": in.Public["Nullifier"]}
+		}, ErrInvalidProof},
+	} {
+		t.Run(name, func(t *testing.T) {
+			changed := *in
+			c.change(&changed)
+			_, err := u.VerifyExternalProof(&changed)
+			assertIs(t, err, c.err)
+		})
+	}
+
+	// a revoked proof is not issued again, whoever submits it
+	token, err := u.readProofToken(models2.HashAlgorithm, data.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := models2.Lookup(models2.HashAlgorithm)
+	nullifier, err := proofNullifier(def, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RevokeNullifier(&models.SpentNullifier{Circuit: def.Name, Nullifier: nullifier, CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.VerifyExternalProof(in)
+	assertIs(t, err, ErrProofRevoked)
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// proofHandleV0 handles are the base64 of the public witness, || and the customer id,
+	// they are told apart by their first byte, the high byte of the witness length
+	proofHandleV0 = 0
+	// proofHandleV1 handles are the base64 of the version byte followed by the circuit,
+	// the customer id and a random nonce, each prefixed by its uvarint length. The
+	// customer id is empty for anonymous circuits.
+	proofHandleV1 = 1
+
+	proofHandleNonceSize = 16
+)
+
+// proofHandle is what a Redis proof token says about the proof it refers to
+type proofHandle struct {
+	version    byte
+	circuit    string // empty in v0 handles
+	customerId string // empty for anonymous circuits
+	nonce      []byte // the public witness in v0 handles
+}
+
+// newProofHandle returns a handle of the current version for a proof of the customer
+func newProofHandle(circuit, customerId string) (*proofHandle, error) {
+	nonce := make([]byte, proofHandleNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &proofHandle{version: proofHandleV1, circuit: circuit, customerId: customerId, nonce: nonce}, nil
+}
+
+// String returns the token handed to the client, the handle is also the Redis key prefix
+func (h *proofHandle) String() string {
+	data := []byte{h.version}
+	for _, field := range [][]byte{[]byte(h.circuit), []byte(h.customerId), h.nonce} {
+		data = binary.AppendUvarint(data, uint64(len(field)))
+		data = append(data, field...)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// parseProofHandle reads a Redis proof token, v0 handles are only accepted when legacy is set
+func parseProofHandle(code string, legacy bool) (*proofHandle, error) {
+	data, err := base64.StdEncoding.DecodeString(code)
+	if err != nil {
+		return nil, fmt.Errorf("%w : not base64", ErrInvalidProofToken)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w : empty", ErrInvalidProofToken)
+	}
+
+	switch data[0] {
+	case proofHandleV0:
+		if !legacy {
+			return nil, fmt.Errorf("%w : %d", ErrProofTokenVersion, data[0])
+		}
+		return parseProofHandleV0(data)
+	case proofHandleV1:
+	default:
+		return nil, fmt.Errorf("%w : %d", ErrProofTokenVersion, data[0])
+	}
+
+	h := &proofHandle{version: data[0]}
+	rest := data[1:]
+	fields := []string{"circuit", "customer id", "nonce"}
+	values := make([][]byte, len(fields))
+	for i, name := range fields {
+		n, size := binary.Uvarint(rest)
+		if size <= 0 {
+			return nil, fmt.Errorf("%w : %s length is truncated", ErrInvalidProofToken, name)
+		}
+		rest = rest[size:]
+		if (n == 0 && name != "customer id") || n > uint64(len(rest)) {
+			return nil, fmt.Errorf("%w : %s length %d out of bounds", ErrInvalidProofToken, name, n)
+		}
+		values[i], rest = rest[:n], rest[n:]
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w : %d trailing bytes", ErrInvalidProofToken, len(rest))
+	}
+	h.circuit, h.customerId, h.nonce = string(values[0]), string(values[1]), values[2]
+	return h, nil
+}
+
+// parseProofHandleV0 splits a v0 handle on its last ||, the witness before it may hold
+// the separator while customer ids never do
+func parseProofHandleV0(data []byte) (*proofHandle, error) {
+	i := bytes.LastIndex(data, []byte("||"))
+	if i <= 0 || i+2 == len(data) {
+		return nil, fmt.Errorf("%w : v0 handle without a customer id", ErrInvalidProofToken)
+	}
+	return &proofHandle{version: proofHandleV0, customerId: string(data[i+2:]), nonce: data[:i]}, nil
+}
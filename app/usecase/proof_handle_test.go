@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func encodeHandle(data ...byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestParseProofHandle(t *testing.T) {
+	v1, err := newProofHandle("hash||x", "cust||1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymous, err := newProofHandle("membership", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// v0 handles start with the high byte of the witness length, it may hold the separator
+	v0 := append([]byte{0, 0, 0, 1, '|', '|', 7}, []byte("||cust-1")...)
+
+	for _, c := range []struct {
+		name     string
+		code     string
+		legacy   bool
+		err      error
+		circuit  string
+		customer string
+	}{
+		{name: "v1", code: v1.String(), circuit: "hash||x", customer: "cust||1"},
+		{name: "v1 anonymous", code: anonymous.String(), circuit: "membership"},
+		{name: "not base64", code: "not base64!", err: ErrInvalidProofToken},
+		{name: "empty", code: "", err: ErrInvalidProofToken},
+		{name: "unknown version", code: encodeHandle(2, 1, 'h', 0, 1, 'n'), err: ErrProofTokenVersion},
+		{name: "truncated circuit length", code: encodeHandle(1, 0x80), err: ErrInvalidProofToken},
+		{name: "truncated nonce length", code: encodeHandle(1, 1, 'h', 0, 0xff), err: ErrInvalidProofToken},
+		{name: "missing customer length", code: encodeHandle(1, 1, 'h'), err: ErrInvalidProofToken},
+		{name: "length past the end", code: encodeHandle(1, 9, 'h'), err: ErrInvalidProofToken},
+		{name: "overflowing length", code: encodeHandle(1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01), err: ErrInvalidProofToken},
+		{name: "empty circuit", code: encodeHandle(1, 0, 0, 1, 'n'), err: ErrInvalidProofToken},
+		{name: "empty nonce", code: encodeHandle(1, 1, 'h', 0, 0), err: ErrInvalidProofToken},
+		{name: "trailing bytes", code: encodeHandle(1, 1, 'h', 0, 1, 'n', 'x'), err: ErrInvalidProofToken},
+		{name: "legacy v0", code: encodeHandle(v0...), legacy: true, customer: "cust-1"},
+		{name: "v0 without legacy", code: encodeHandle(v0...), err: ErrProofTokenVersion},
+		{name: "v0 without customer", code: encodeHandle(0, 0, 0, 1, 7, '|', '|'), legacy: true, err: ErrInvalidProofToken},
+		{name: "v0 without separator", code: encodeHandle(0, 0, 0, 1, 7), legacy: true, err: ErrInvalidProofToken},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h, err := parseProofHandle(c.code, c.legacy)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("expected %v, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.circuit != c.circuit || h.customerId != c.customer {
+				t.Fatalf("read circuit %q and customer %q", h.circuit, h.customerId)
+			}
+		})
+	}
+
+	// the v1 nonce and the v0 witness come back whole
+	h, _ := parseProofHandle(v1.String(), false)
+	if !bytes.Equal(h.nonce, v1.nonce) {
+		t.Fatal("v1 nonce not read back")
+	}
+	h, _ = parseProofHandle(encodeHandle(v0...), true)
+	if !bytes.Equal(h.nonce, v0[:len(v0)-8]) {
+		t.Fatalf("v0 witness read as %v", h.nonce)
+	}
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+)
+
+// SubmitProofJob records a proof to generate in the background and returns the job to
+// poll, the proof token is on the job once it is done. A job only takes an algorithm and a
+// customer, circuits bound to a payment intent and anonymous circuits need more and are
+// refused before the job is queued, aggregators are not even looked up.
+func (u *Usecase) SubmitProofJob(in *models.ProofJobRequest) (data *models.ProofJob, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case def.CheckIntent != nil:
+		return nil, fmt.Errorf("%w : %s proves a payment intent", ErrJobNotSupported, def.Name)
+	case def.Anonymous:
+		return nil, fmt.Errorf("%w : %s is anonymous", ErrJobNotSupported, def.Name)
+	}
+	cData, err := u.db.GetCustomerData(in.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+	if cData.Id == "" {
+		return nil, ErrCustomerNotFound
+	}
+
+	id, err := u.db.InsertProofJob(&models.ProofJob{Algo: def.Name, CustomerId: cData.Id, PartnerId: in.PartnerId})
+	if err != nil {
+		return nil, err
+	}
+	if err = u.jobs.Enqueue(id); err != nil {
+		// the job would only run after a restart, fail it so the caller submits again
+		if finishErr := u.db.FinishProofJob(id, models.ProofJobFailed, "", err.Error()); finishErr != nil {
+			log.WithField("error", finishErr).Errorf("Unable to fail proof job %s", id)
+		}
+		return nil, err
+	}
+	return u.db.GetProofJob(id)
+}
+
+// GetProofJob returns a job of the partner, jobs of other partners are not found
+func (u *Usecase) GetProofJob(in *models.ProofJobIdRequest) (data *models.ProofJob, err error) {
+	data, err = u.db.GetProofJob(in.Id)
+	if err != nil {
+		return nil, err
+	}
+	if data.Id == "" || data.PartnerId != in.PartnerId {
+		return nil, ErrJobNotFound
+	}
+	return data, nil
+}
+
+// StartProofJobs starts the workers, then enqueues the jobs left queued or interrupted by
+// a restart. The keys must be loaded, jobs are proved as soon as they are dequeued.
+func (u *Usecase) StartProofJobs() error {
+	u.jobs.Start(u.runProofJob)
+
+	ids, err := u.db.RequeueProofJobs()
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if err = u.jobs.Enqueue(id); err != nil {
+			// the remaining jobs stay queued until the next restart
+			log.WithField("error", err).Warnf("%d proof jobs not resumed", len(ids)-i)
+			return nil
+		}
+	}
+	if len(ids) > 0 {
+		log.Infof("%d proof jobs resumed", len(ids))
+	}
+	return nil
+}
+
+// runProofJob proves a dequeued job and records the proof token or the failure
+func (u *Usecase) runProofJob(id string) {
+	claimed, err := u.db.ClaimProofJob(id)
+	if err != nil {
+		log.WithField("error", err).Errorf("Unable to claim proof job %s", id)
+		return
+	}
+	if !claimed {
+		return
+	}
+	job, err := u.db.GetProofJob(id)
+	if err != nil {
+		log.WithField("error", err).Errorf("Unable to read proof job %s", id)
+		return
+	}
+
+	status, hash, message := models.ProofJobDone, "", ""
+	proof, err := u.proveJob(job)
+	if err != nil {
+		status, message = models.ProofJobFailed, err.Error()
+	} else {
+		hash = proof.Hash
+	}
+	if err = u.db.FinishProofJob(id, status, hash, message); err != nil {
+		log.WithField("error", err).Errorf("Unable to record proof job %s", id)
+	}
+}
+
+// proveJob proves the job for its customer, the token is issued to the partner of the job
+func (u *Usecase) proveJob(job *models.ProofJob) (*models.ProofResponse, error) {
+	def, err := models2.Lookup(job.Algo)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := u.keys.GetKeys(def.Name, def.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return u.prove(def, keys, job.CustomerId, "", nil, job.PartnerId)
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+func TestSubmitProofJobRefusesCircuits(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	for _, algo := range []string{
+		models2.PaymentAlgorithm,
+		models2.RangeAlgorithm,
+		models2.MembershipAlgorithm,
+	} {
+		_, err := u.SubmitProofJob(&models.ProofJobRequest{Algo: algo, CustomerId: "cust-1", PartnerId: "partner-1"})
+		assertIs(t, err, ErrJobNotSupported)
+	}
+	_, err := u.SubmitProofJob(&models.ProofJobRequest{Algo: models2.PaymentBLS12377Algorithm + models2.AggregateSuffix, CustomerId: "cust-1"})
+	assertIs(t, err, models2.ErrAlgorithmNotFound)
+}
+
+func TestProofJobOfPartner(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	job, err := u.SubmitProofJob(&models.ProofJobRequest{Algo: models2.HashAlgorithm, CustomerId: "cust-1", PartnerId: "partner-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := u.jobs.(*fakeQueue); !ok || len(u.jobs.(*fakeQueue).ids) != 1 {
+		t.Fatal("job not enqueued")
+	}
+	u.runProofJob(job.Id)
+
+	_, err = u.GetProofJob(&models.ProofJobIdRequest{Id: job.Id, PartnerId: "partner-2"})
+	assertIs(t, err, ErrJobNotFound)
+	done, err := u.GetProofJob(&models.ProofJobIdRequest{Id: job.Id, PartnerId: "partner-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.ProofJobDone {
+		t.Fatalf("job %s: %s", done.Status, done.Error)
+	}
+
+	// the token of the job is issued to the partner that submitted it
+	_, err = u.RevokeProof(&models.ProofRequest{Algo: models2.HashAlgorithm, Proof: done.Hash, PartnerId: "partner-2"})
+	assertIs(t, err, ErrProofNotOwned)
+	if _, err = u.RevokeProof(&models.ProofRequest{Algo: models2.HashAlgorithm, Proof: done.Hash, PartnerId: "partner-1"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	log "github.com/sirupsen/logrus"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"time"
+)
+
+// StartMembership builds the membership tree over every customer, then keeps it in sync
+// with the customers table and rebuilds it on the configured intervals
+func (u *Usecase) StartMembership() error {
+	if err := u.RebuildMembership(); err != nil {
+		return err
+	}
+
+	if u.cfg.MembershipSyncInterval > 0 {
+		go func() {
+			for range time.Tick(time.Duration(u.cfg.MembershipSyncInterval) * time.Second) {
+				if err := u.SyncMembership(); err != nil {
+					log.WithField("error", err).Error("Unable to sync the membership tree")
+				}
+			}
+		}()
+	}
+	if u.cfg.MembershipReconcileInterval > 0 {
+		go func() {
+			for range time.Tick(time.Duration(u.cfg.MembershipReconcileInterval) * time.Second) {
+				if err := u.RebuildMembership(); err != nil {
+					log.WithField("error", err).Error("Unable to rebuild the membership tree")
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// SyncMembership applies the customers inserted, enrolled or soft deleted since the last
+// sync to the membership tree. Rows committed after a later one fall behind the
+// watermark, the tree is rebuilt when it holds fewer customers than the table.
+func (u *Usecase) SyncMembership() error {
+	customers, err := u.db.GetCustomersChangedSince(u.members.Since())
+	if err != nil {
+		return err
+	}
+	if err = u.members.Apply(customers); err != nil {
+		return err
+	}
+
+	count, err := u.db.CountCustomers()
+	if err != nil {
+		return err
+	}
+	if held := u.members.Customers(); int64(held) < count {
+		log.WithFields(log.Fields{"customers": count, "leaves": held}).Warn("Membership tree missed customers, rebuilding it")
+		return u.RebuildMembership()
+	}
+	return nil
+}
+
+// RebuildMembership builds the membership tree again over every customer, changes the
+// syncs missed show as a root other than the one applied so far
+func (u *Usecase) RebuildMembership() error {
+	def, err := models2.Lookup(models2.MembershipAlgorithm)
+	if err != nil {
+		return err
+	}
+	customers, err := u.db.GetCustomersChangedSince(time.Time{})
+	if err != nil {
+		return err
+	}
+
+	previous, _ := u.members.Root()
+	if err = u.members.Rebuild(def.Curve, models2.MembershipDepth, customers); err != nil {
+		return err
+	}
+	if current, err := u.members.Root(); err == nil && previous != nil && current.Root != previous.Root {
+		log.WithFields(log.Fields{"previous": previous.Root, "root": current.Root}).Warn("Membership tree rebuilt on another root")
+	}
+	return nil
+}
+
+// GetMembershipRoot returns the current root of the membership tree, membership proofs
+// are made against it
+func (u *Usecase) GetMembershipRoot() (data *models.MembershipRoot, err error) {
+	return u.members.Root()
+}
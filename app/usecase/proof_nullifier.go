@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"fmt"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+)
+
+// SpendProof verifies a proof token for the payment intent and records its nullifier as
+// spent, any later proof with the same nullifier is rejected with ErrProofSpent. Only
+// circuits binding their proofs to the intent can authorize a payment.
+func (u *Usecase) SpendProof(algo string, code string, intent *models.PaymentIntent) (customerId string, err error) {
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return "", err
+	}
+	if def.CheckIntent == nil {
+		return "", fmt.Errorf("%w : %s", models2.ErrPaymentNotSupported, def.Name)
+	}
+	token, err := u.verifyProof(algo, code)
+	if err != nil {
+		return "", err
+	}
+
+	cData, err := u.db.GetCustomerData(token.customerId)
+	if err != nil {
+		return "", err
+	}
+	in, err := u.witnessInput(def, cData)
+	if err != nil {
+		return "", err
+	}
+	if err = u.setIntent(in, intent); err != nil {
+		return "", err
+	}
+	if err = def.CheckIntent(token.publicWitness, in); err != nil {
+		return "", fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+
+	nullifier, err := proofNullifier(def, token)
+	if err != nil {
+		return "", err
+	}
+	context, err := models2.IntentHash(def.Curve, intent)
+	if err != nil {
+		return "", err
+	}
+	recorded, err := u.db.SpendNullifier(&models.SpentNullifier{
+		Circuit:    def.Name,
+		Nullifier:  nullifier,
+		CustomerId: token.customerId,
+		Context:    &context,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !recorded {
+		return "", fmt.Errorf("%w : nullifier %s or intent %s of %s", ErrProofSpent, nullifier, context, def.Name)
+	}
+	return token.customerId, nil
+}
+
+// proofNullifier returns the nullifier the circuit derives in the proof. Circuits without
+// one cannot be spent nor revoked, a hash of the proof would not do as proofs are malleable.
+func proofNullifier(def *models2.Definition, token *proofToken) (string, error) {
+	if def.Nullifier == nil {
+		return "", fmt.Errorf("%w : %s", ErrNullifierMissing, def.Name)
+	}
+	nullifier, err := def.Nullifier(token.publicWitness)
+	if err != nil {
+		return "", fmt.Errorf("%w : %s", ErrInvalidProof, err.Error())
+	}
+	return nullifier, nil
+}
+
+// setIntent sets the payment intent of the witness and the amount range its partner
+// accepts, an unknown partner has no range
+func (u *Usecase) setIntent(in *models2.WitnessInput, intent *models.PaymentIntent) error {
+	in.Intent = intent
+	if intent == nil || intent.PartnerReferenceNo == "" {
+		return nil
+	}
+	partner, err := u.db.GetUserByReferenceNo(intent.PartnerReferenceNo)
+	if err != nil {
+		return err
+	}
+	if partner.MaxAmount > 0 {
+		in.Range = &models2.AmountRange{Min: partner.MinAmount, Max: partner.MaxAmount}
+		return in.Range.Check()
+	}
+	return nil
+}
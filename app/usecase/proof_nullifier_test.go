@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+func TestSpendProofAfterEnrollment(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	key := holdKey(t, db)
+	in := consent(t, key, paymentRequest("10.00"))
+	first, err := u.GetPaymentProof(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.SpendProof(models2.PaymentAlgorithm, first.Hash, paymentIntent(in)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new salt derives a new nullifier for the same intent, which is still paid
+	if _, err = u.EnrollCommitment(&models.CommitmentRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := u.GetPaymentProof(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Nullifier == first.Nullifier {
+		t.Fatal("enrolling again kept the nullifier")
+	}
+	_, err = u.SpendProof(models2.PaymentAlgorithm, second.Hash, paymentIntent(in))
+	assertIs(t, err, ErrProofSpent)
+
+	other := consent(t, key, paymentRequest("12.00"))
+	third, err := u.GetPaymentProof(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.SpendProof(models2.PaymentAlgorithm, third.Hash, paymentIntent(other)); err != nil {
+		t.Fatalf("another intent refused with %v", err)
+	}
+}
+
+func TestRevokeProofWithoutNullifier(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	if _, err := u.RegisterKey(&models.KeyRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	in := &models.ProofJobRequest{Algo: models2.EddsaAlgorithm, CustomerId: "cust-1", PartnerId: "partner-1"}
+	job, err := u.SubmitProofJob(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.runProofJob(job.Id)
+	done, err := u.GetProofJob(&models.ProofJobIdRequest{Id: job.Id, PartnerId: "partner-1"})
+	if err != nil || done.Hash == "" {
+		t.Fatalf("job %+v: %v", done, err)
+	}
+
+	_, err = u.RevokeProof(&models.ProofRequest{Algo: models2.EddsaAlgorithm, Proof: done.Hash, PartnerId: "partner-1"})
+	assertIs(t, err, ErrNullifierMissing)
+	if result, err := u.VerifyProof(models2.EddsaAlgorithm, done.Hash); err != nil || !result.Valid {
+		t.Fatalf("proof without nullifier not verified: %+v %v", result, err)
+	}
+}
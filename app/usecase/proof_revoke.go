@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"fmt"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"time"
+)
+
+// RevokeProof invalidates a proof token before it expires, only the partner the token was
+// issued to may revoke it, so tokens proved on the open route cannot be revoked and expire.
+// The nullifier of the proof is revoked rather than the token, so tokens issued again for
+// the same proof, such as a re-submitted external proof, are refused too. A Redis handle is
+// deleted along, it then reads as expired.
+func (u *Usecase) RevokeProof(in *models.ProofRequest) (data *models.ProofRevocation, err error) {
+	def, err := models2.Lookup(in.Algo)
+	if err != nil {
+		return nil, err
+	}
+	token, err := u.readProofToken(def.Name, in.Proof)
+	if err != nil {
+		return nil, err
+	}
+	if token.partnerId == "" || token.partnerId != in.PartnerId {
+		return nil, ErrProofNotOwned
+	}
+	nullifier, err := proofNullifier(def, token)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.db.RevokeNullifier(&models.SpentNullifier{
+		Circuit:    def.Name,
+		Nullifier:  nullifier,
+		CustomerId: token.customerId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = u.deleteProofHandle(in.Proof); err != nil {
+		return nil, err
+	}
+
+	return &models.ProofRevocation{
+		Circuit:    def.Name,
+		Nullifier:  nullifier,
+		CustomerId: token.customerId,
+		RevokedAt:  time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// checkRevoked refuses a token whose proof nullifier was revoked, proofs of circuits
+// without a nullifier cannot be revoked
+func (u *Usecase) checkRevoked(def *models2.Definition, token *proofToken) error {
+	if def.Nullifier == nil {
+		return nil
+	}
+	nullifier, err := proofNullifier(def, token)
+	if err != nil {
+		return err
+	}
+	revoked, err := u.db.IsNullifierRevoked(def.Name, nullifier)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("%w : nullifier %s of %s", ErrProofRevoked, nullifier, def.Name)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"strings"
+	"testing"
+)
+
+func TestRevokeProof(t *testing.T) {
+	for _, mode := range []string{ProofTokenRedis, ProofTokenStateless} {
+		t.Run(mode, func(t *testing.T) {
+			u, db, redis := newTestUsecase(t)
+			u.cfg.ProofTokenMode = mode
+			in := paymentRequest("10.00")
+			in.PartnerId = "partner-1"
+			data, err := u.GetPaymentProof(consent(t, holdKey(t, db), in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			revoke := &models.ProofRequest{Algo: models2.PaymentAlgorithm, Proof: data.Hash, PartnerId: "partner-2"}
+			_, err = u.RevokeProof(revoke)
+			assertIs(t, err, ErrProofNotOwned)
+			revoke.PartnerId = ""
+			_, err = u.RevokeProof(revoke)
+			assertIs(t, err, ErrProofNotOwned)
+
+			revoke.PartnerId = "partner-1"
+			revocation, err := u.RevokeProof(revoke)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revocation.Nullifier != data.Nullifier {
+				t.Fatalf("revoked nullifier %s, the proof has %s", revocation.Nullifier, data.Nullifier)
+			}
+
+			// a deleted handle reads as expired, a signed token carries its revoked nullifier
+			_, err = u.SpendProof(models2.PaymentAlgorithm, data.Hash, paymentIntent(in))
+			if mode == ProofTokenRedis {
+				assertIs(t, err, ErrProofTokenExpired)
+				for key := range redis.values {
+					if strings.HasPrefix(key, data.Hash) {
+						t.Fatalf("redis key %s left after revocation", key)
+					}
+				}
+			} else {
+				assertIs(t, err, ErrProofRevoked)
+			}
+		})
+	}
+}
+
+func TestRevokeOpenRouteProof(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	data, err := u.GetProof(models2.HashAlgorithm, "cust-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.RevokeProof(&models.ProofRequest{Algo: models2.HashAlgorithm, Proof: data.Hash, PartnerId: "partner-1"})
+	assertIs(t, err, ErrProofNotOwned)
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"encoding/hex"
+	"github.com/consensys/gnark-crypto/signature"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"strings"
+	"testing"
+)
+
+func paymentRequest(amount string) *models.PaymentProofRequest {
+	in := &models.PaymentProofRequest{CustomerId: "cust-1", PartnerReferenceNo: "P-1", ExternalId: "ext-1"}
+	in.Amount.Value, in.Amount.Currency = amount, "IDR"
+	return in
+}
+
+func paymentIntent(in *models.PaymentProofRequest) *models.PaymentIntent {
+	return &models.PaymentIntent{PartnerReferenceNo: in.PartnerReferenceNo, Amount: in.Amount.Value, Currency: in.Amount.Currency, ExternalId: in.ExternalId}
+}
+
+// holdKey registers a key the customer holds, the service only keeps its public half
+func holdKey(t *testing.T, db *fakeDb) signature.Signer {
+	t.Helper()
+	key, err := models2.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.keys["cust-1"] = &models.CustomerKey{CustomerId: "cust-1", PublicKey: hex.EncodeToString(key.Public().Bytes())}
+	return key
+}
+
+func consent(t *testing.T, key signature.Signer, in *models.PaymentProofRequest) *models.PaymentProofRequest {
+	t.Helper()
+	var err error
+	if in.Consent, err = models2.SignConsent(key, paymentIntent(in)); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func TestGetPaymentProofConsent(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	in := paymentRequest("10.00")
+
+	// no key at all, then a key the service generated and could sign with
+	_, err := u.GetPaymentProof(in)
+	assertIs(t, err, ErrConsentMissing)
+	if _, err = u.RegisterKey(&models.KeyRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.GetPaymentProof(in)
+	assertIs(t, err, ErrConsentMissing)
+
+	key := holdKey(t, db)
+	_, err = u.GetPaymentProof(in)
+	assertIs(t, err, ErrConsentMissing)
+
+	other := paymentRequest("10.01")
+	if in.Consent, err = models2.SignConsent(key, paymentIntent(other)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.GetPaymentProof(in)
+	assertIs(t, err, ErrConsentMissing)
+
+	data, err := u.GetPaymentProof(consent(t, key, in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.SpendProof(models2.PaymentAlgorithm, data.Hash, paymentIntent(in)); err != nil {
+		t.Fatalf("consented proof refused with %v", err)
+	}
+}
+
+func TestGetRangeProof(t *testing.T) {
+	u, db, _ := newTestUsecase(t)
+	_, err := u.GetRangeProof(paymentRequest("10.00"))
+	assertIs(t, err, ErrConsentMissing)
+
+	key := holdKey(t, db)
+	first, err := u.GetRangeProof(consent(t, key, paymentRequest("10.00")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := u.GetRangeProof(consent(t, key, paymentRequest("10.0")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same intent however its amount is spelled, yet proofs of one balance differ
+	if first.Public["Context"] != second.Public["Context"] {
+		t.Fatalf("contexts %s and %s differ for one intent", first.Public["Context"], second.Public["Context"])
+	}
+	if first.Public["BalanceCommitment"] == second.Public["BalanceCommitment"] {
+		t.Fatal("two proofs carry the same balance commitment")
+	}
+
+	intent := paymentIntent(paymentRequest("10"))
+	if _, err = u.SpendProof(models2.RangeAlgorithm, first.Hash, intent); err != nil {
+		t.Fatalf("range proof refused with %v", err)
+	}
+	_, err = u.SpendProof(models2.RangeAlgorithm, second.Hash, intent)
+	assertIs(t, err, ErrProofSpent)
+}
+
+func TestVerifyProofReasons(t *testing.T) {
+	u, db, redis := newTestUsecase(t)
+	proof := func(context string) string {
+		t.Helper()
+		data, err := u.GetProof(models2.HashAlgorithm, "cust-1", context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data.Hash
+	}
+	reason := func(algo, code, expected string) {
+		t.Helper()
+		result, err := u.VerifyProof(algo, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Valid || result.Reason != expected || result.Message == "" {
+			t.Fatalf("%+v, expected reason %s", result, expected)
+		}
+	}
+
+	valid := proof("ref-1")
+	result, err := u.VerifyProof(models2.HashAlgorithm, valid)
+	if err != nil || !result.Valid || result.Circuit != models2.HashAlgorithm || result.CustomerId != "cust-1" || result.Public["Nullifier"] == "" {
+		t.Fatalf("%+v %v", result, err)
+	}
+	_, err = u.VerifyProof("sha1", valid)
+	assertIs(t, err, models2.ErrAlgorithmNotFound)
+
+	reason(models2.HashAlgorithm, "not a token", models.ReasonMalformedToken)
+	reason(models2.PaymentAlgorithm, valid, models.ReasonCircuitMismatch)
+
+	expired := proof("ref-2")
+	if err = redis.Del(expired + "_proof"); err != nil {
+		t.Fatal(err)
+	}
+	reason(models2.HashAlgorithm, expired, models.ReasonExpired)
+
+	token, err := u.readProofToken(models2.HashAlgorithm, valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, _ := models2.Lookup(models2.HashAlgorithm)
+	nullifier, err := proofNullifier(def, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RevokeNullifier(&models.SpentNullifier{Circuit: def.Name, Nullifier: nullifier, CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	reason(models2.HashAlgorithm, valid, models.ReasonRevoked)
+
+	enrolled := proof("ref-3")
+	if _, err = u.EnrollCommitment(&models.CommitmentRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	reason(models2.HashAlgorithm, enrolled, models.ReasonPublicInputMismatch)
+
+	removed := proof("ref-4")
+	delete(db.customers, "cust-1")
+	reason(models2.HashAlgorithm, removed, models.ReasonCustomerNotFound)
+}
+
+func TestGetPublicSchema(t *testing.T) {
+	u, _, _ := newTestUsecase(t)
+	schema, err := u.GetPublicSchema(models2.HashAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, input := range schema.Inputs {
+		names = append(names, input.Name)
+	}
+	if strings.Join(names, ",") != "Hash,Context,Nullifier" || schema.Field == "" {
+		t.Fatalf("schema %+v", schema)
+	}
+
+	// aggregators publish their schema under the name of the circuit they fold
+	agg, err := u.GetPublicSchema(models2.PaymentBLS12377Algorithm + models2.AggregateSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agg.Curve != "bw6_761" || agg.Inputs[len(agg.Inputs)-1].Name != "Count" {
+		t.Fatalf("aggregator schema %+v", agg)
+	}
+	_, err = u.GetPublicSchema("sha1" + models2.AggregateSuffix)
+	assertIs(t, err, models2.ErrAlgorithmNotFound)
+}
@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/golang-jwt/jwt"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"strings"
+	"time"
+)
+
+const (
+	// ProofTokenRedis keeps the proof in Redis and returns a handle to it
+	ProofTokenRedis = "redis"
+	// ProofTokenStateless embeds the proof in a token signed by the service
+	ProofTokenStateless = "stateless"
+)
+
+// proofToken is the proof and public witness a token refers to
+type proofToken struct {
+	circuit       string
+	curve         ecc.ID
+	backend       backend.ID
+	customerId    string
+	partnerId     string // empty for tokens proved on the open route
+	proof         models2.Proof
+	publicWitness witness.Witness
+}
+
+// tokenSubject returns the customer id a token of the circuit carries, none for anonymous circuits
+func tokenSubject(def *models2.Definition, cData *models.Customer) string {
+	if def.Anonymous {
+		return ""
+	}
+	return cData.Id
+}
+
+// proofTokenTTL returns how long tokens of the circuit stay valid
+func (u *Usecase) proofTokenTTL(circuit string) time.Duration {
+	if ttl, ok := u.cfg.ProofTokenCircuitTTL[circuit]; ok && ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return time.Duration(u.cfg.ProofTokenTTL) * time.Second
+}
+
+// issueProofToken returns the token handed to the client for a generated proof and when
+// it expires, the partner it is issued to is the only one allowed to revoke it
+func (u *Usecase) issueProofToken(circuit string, curve ecc.ID, b backend.ID, customerId, partnerId string, proof, publicWitness []byte) (string, time.Time, error) {
+	ttl := u.proofTokenTTL(circuit)
+	expiresAt := time.Now().Add(ttl)
+	if u.cfg.ProofTokenMode == ProofTokenStateless {
+		token, err := u.signProofToken(circuit, curve, b, customerId, partnerId, proof, publicWitness, expiresAt)
+		return token, expiresAt, err
+	}
+
+	handle, err := newProofHandle(circuit, customerId)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	dataResponse := handle.String()
+
+	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "proof"), string(proof), ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "witness"), string(publicWitness), ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// handles issued without a backend key were all groth16 proofs
+	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "backend"), b.String(), ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// and without a curve key BN254 proofs
+	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "curve"), curve.String(), ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if partnerId != "" {
+		err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "partner"), partnerId, ttl)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	return dataResponse, expiresAt, nil
+}
+
+// signProofToken embeds the proof in a RS256 JWT so it can be verified without Redis
+func (u *Usecase) signProofToken(circuit string, curve ecc.ID, b backend.ID, customerId, partnerId string, proof, publicWitness []byte, expiresAt time.Time) (string, error) {
+	privKey, err := internal.GeneratePrivateKey(u.cfg)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &models.ProofClaims{
+		Circuit:       circuit,
+		Backend:       b.String(),
+		Curve:         curve.String(),
+		Partner:       partnerId,
+		Proof:         proof,
+		PublicWitness: publicWitness,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   customerId,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privKey)
+}
+
+// readProofToken resolves both token formats, whatever the configured mode, so
+// tokens issued before a mode switch stay verifiable until they expire.
+// An empty circuit accepts a token of any circuit.
+func (u *Usecase) readProofToken(circuit, code string) (*proofToken, error) {
+	return u.resolveProofToken(u.publicKey, circuit, code)
+}
+
+// resolveProofToken reads a token like readProofToken, stateless tokens are checked
+// against the key publicKey returns
+func (u *Usecase) resolveProofToken(publicKey func() (*rsa.PublicKey, error), circuit, code string) (*proofToken, error) {
+	if isStatelessToken(code) {
+		return u.parseProofToken(publicKey, circuit, code)
+	}
+
+	handle, err := parseProofHandle(code, u.cfg.ProofTokenLegacy)
+	if err != nil {
+		return nil, err
+	}
+	if handle.circuit != "" && circuit != "" && handle.circuit != circuit {
+		return nil, fmt.Errorf("%w : %s", ErrProofTokenCircuit, handle.circuit)
+	}
+
+	// get proof
+	val, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "proof"))
+	if errors.Is(err, ErrCacheMiss) {
+		return nil, ErrProofTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	// get witness
+	public, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "witness"))
+	if errors.Is(err, ErrCacheMiss) {
+		return nil, ErrProofTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// v0 handles carry no circuit, a token of another circuit only shows as an invalid proof
+	b := backend.GROTH16
+	if name, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "backend")); err == nil {
+		if b, err = models2.ParseBackend(name); err != nil {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+		}
+	}
+
+	curve := ecc.BN254
+	if name, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "curve")); err == nil {
+		if curve, err = models2.ParseCurve(name); err != nil {
+			return nil, fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+		}
+	}
+
+	if handle.circuit != "" {
+		circuit = handle.circuit
+	}
+	token := &proofToken{circuit: circuit, curve: curve, backend: b, customerId: handle.customerId}
+	if partnerId, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "partner")); err == nil {
+		token.partnerId = partnerId
+	}
+	if err = token.decode([]byte(val), []byte(public)); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (u *Usecase) parseProofToken(publicKey func() (*rsa.PublicKey, error), circuit, code string) (*proofToken, error) {
+	claims := &models.ProofClaims{}
+	_, err := jwt.ParseWithClaims(code, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return publicKey()
+	})
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+		return nil, ErrProofTokenExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+	if circuit != "" && claims.Circuit != circuit {
+		return nil, fmt.Errorf("%w : %s", ErrProofTokenCircuit, claims.Circuit)
+	}
+
+	b, err := models2.ParseBackend(claims.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+
+	curve, err := models2.ParseCurve(claims.Curve)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+
+	token := &proofToken{circuit: claims.Circuit, curve: curve, backend: b, customerId: claims.Subject, partnerId: claims.Partner}
+	if err = token.decode(claims.Proof, claims.PublicWitness); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// decode reads the proof of the token curve and backend and the public witness
+func (t *proofToken) decode(proof, publicWitness []byte) (err error) {
+	if t.proof, err = models2.NewProof(t.curve, t.backend); err != nil {
+		return fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+	if _, err = t.proof.ReadFrom(bytes.NewReader(proof)); err != nil {
+		return fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+
+	if err = checkWitnessLength(t.curve, publicWitness); err != nil {
+		return fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+	t.publicWitness, _ = witness.New(t.curve.ScalarField())
+	if err = t.publicWitness.UnmarshalBinary(publicWitness); err != nil {
+		return fmt.Errorf("%w : %s", ErrInvalidProofToken, err.Error())
+	}
+	return nil
+}
+
+// checkWitnessLength compares the length a binary witness declares with its size, gnark
+// allocates the declared length before reading the elements
+func checkWitnessLength(curve ecc.ID, data []byte) error {
+	// number of public and secret values, then the vector length and its elements
+	const header = 12
+	if len(data) < header {
+		return errors.New("witness too short")
+	}
+	elementSize := (curve.ScalarField().BitLen() + 7) / 8
+	if n := binary.BigEndian.Uint32(data[8:header]); uint64(len(data)-header) != uint64(n)*uint64(elementSize) {
+		return fmt.Errorf("witness declares %d values in %d bytes", n, len(data)-header)
+	}
+	return nil
+}
+
+// deleteProofHandle removes the Redis keys of a handle, stateless tokens have none
+func (u *Usecase) deleteProofHandle(code string) error {
+	if isStatelessToken(code) {
+		return nil
+	}
+	var keys []string
+	for _, suffix := range []string{"proof", "witness", "backend", "curve", "partner"} {
+		keys = append(keys, fmt.Sprintf("%s_%s", code, suffix))
+	}
+	return u.redis.Del(keys...)
+}
+
+// isStatelessToken tells a JWT apart from a base64 Redis handle, which never contains a dot
+func isStatelessToken(code string) bool {
+	return strings.Count(code, ".") == 2
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/consensys/gnark/backend"
+	"github.com/golang-jwt/jwt"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+	"time"
+)
+
+func TestStatelessProofToken(t *testing.T) {
+	u, _, redis := newTestUsecase(t)
+	u.cfg.ProofTokenMode = ProofTokenStateless
+	data, err := u.GetProof(models2.HashAlgorithm, "cust-1", "ref-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token carries the proof, nothing is read from redis
+	redis.values = nil
+	if result, err := u.VerifyProof(models2.HashAlgorithm, data.Hash); err != nil || !result.Valid {
+		t.Fatalf("stateless token not verified: %+v %v", result, err)
+	}
+	_, err = u.readProofToken(models2.PaymentAlgorithm, data.Hash)
+	assertIs(t, err, ErrProofTokenCircuit)
+
+	token, err := u.readProofToken(models2.HashAlgorithm, data.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &models.ProofClaims{Circuit: models2.HashAlgorithm, Backend: backend.GROTH16.String(), Curve: token.curve.String()}
+	claims.Subject, claims.ExpiresAt = "cust-1", time.Now().Add(time.Minute).Unix()
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.readProofToken(models2.HashAlgorithm, forged)
+	assertIs(t, err, ErrInvalidProofToken)
+
+	// a shared secret must not stand in for the service key
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(u.cfg.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.readProofToken(models2.HashAlgorithm, hmac)
+	assertIs(t, err, ErrInvalidProofToken)
+
+	_, err = u.readProofToken(models2.HashAlgorithm, data.Hash[:len(data.Hash)-4]+"AAAA")
+	assertIs(t, err, ErrInvalidProofToken)
+
+	expired, err := u.signProofToken(models2.HashAlgorithm, token.curve, token.backend, "cust-1", "", nil, nil, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.readProofToken(models2.HashAlgorithm, expired)
+	assertIs(t, err, ErrProofTokenExpired)
+}
+
+func TestRedisProofTokenExpired(t *testing.T) {
+	u, _, redis := newTestUsecase(t)
+	data, err := u.GetProof(models2.HashAlgorithm, "cust-1", "ref-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.readProofToken(models2.HashAlgorithm, data.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if err = redis.Del(data.Hash + "_witness"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.readProofToken(models2.HashAlgorithm, data.Hash)
+	assertIs(t, err, ErrProofTokenExpired)
+}
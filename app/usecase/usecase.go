@@ -1,7 +1,6 @@
 package usecase
 
 import (
-	"bytes"
 	"crypto"
 	"crypto/hmac"
 	"crypto/rand"
@@ -9,17 +8,11 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/consensys/gnark-crypto/ecc"
-	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
-	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
-	eddsa2 "github.com/consensys/gnark-crypto/signature/eddsa"
-	"github.com/consensys/gnark/backend/groth16"
-	witness2 "github.com/consensys/gnark/backend/witness"
-	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/backend"
 	"github.com/golang-jwt/jwt"
+	"math/big"
 	"smart-contract-service/configuration"
 	"smart-contract-service/internal"
 	"smart-contract-service/models"
@@ -29,16 +22,28 @@ import (
 )
 
 type Usecase struct {
-	redis RedisRepository
-	db    DbRepository
-	cfg   configuration.ConfigApp
+	redis   RedisRepository
+	db      DbRepository
+	keys    KeyRepository
+	jobs    JobQueue
+	members MembershipRepository
+	cfg     configuration.ConfigApp
+
+	// publicKey reads the service key stateless proof tokens are signed with
+	publicKey func() (*rsa.PublicKey, error)
 }
 
-func NewUsecase(redis RedisRepository, db DbRepository, cfg configuration.ConfigApp) *Usecase {
+func NewUsecase(redis RedisRepository, db DbRepository, keys KeyRepository, jobs JobQueue, members MembershipRepository, cfg configuration.ConfigApp) *Usecase {
 	return &Usecase{
-		redis: redis,
-		db:    db,
-		cfg:   cfg,
+		redis:   redis,
+		db:      db,
+		keys:    keys,
+		jobs:    jobs,
+		members: members,
+		cfg:     cfg,
+		publicKey: func() (*rsa.PublicKey, error) {
+			return internal.GeneratePublicKey(cfg)
+		},
 	}
 }
 
@@ -48,12 +53,27 @@ type InputPort interface {
 	RefreshToken(input *models.RefreshTokenRequest) (out *models.LoginResponse, err error)
 	TokenSign(input *models.TokenRequest) (out string, err error)
 	TokenHMAC(input *models.TokenRequest) (out string, err error)
-	GetEllipticProof(id string) (data *models.ProofResponse, err error)
-	GetHashProof(id string) (data *models.ProofResponse, err error)
-	GetEddsaProof(id string) (data *models.ProofResponse, err error)
-	VerifyEllipticProof(code string) (string, bool)
-	VerifyHashProof(code string) (string, bool)
-	VerifyEddsaProof(code string) (string, bool)
+	GetProof(algo string, id string, context string) (data *models.ProofResponse, err error)
+	GetPaymentProof(in *models.PaymentProofRequest) (data *models.ProofResponse, err error)
+	GetRangeProof(in *models.PaymentProofRequest) (data *models.ProofResponse, err error)
+	GetBatchProof(in *models.BatchProofRequest) (data *models.BatchProofResponse, err error)
+	SubmitProofJob(in *models.ProofJobRequest) (data *models.ProofJob, err error)
+	GetProofJob(in *models.ProofJobIdRequest) (data *models.ProofJob, err error)
+	VerifyProof(algo string, code string) (data *models.VerificationResult, err error)
+	SpendProof(algo string, code string, intent *models.PaymentIntent) (customerId string, err error)
+	VerifyBatchProof(in *models.BatchVerifyRequest) (data *models.BatchVerifyResponse, err error)
+	AggregateProof(in *models.AggregateProofRequest) (data *models.AggregateProofResponse, err error)
+	VerifyAggregateProof(in *models.AggregateVerifyRequest) (data *models.AggregateVerifyResponse, err error)
+	GetProofCalldata(code string) (data *models.ProofCalldata, err error)
+	RevokeProof(in *models.ProofRequest) (data *models.ProofRevocation, err error)
+	VerifyExternalProof(in *models.ExternalProofRequest) (data *models.ProofResponse, err error)
+	GetCircuitArtifact(algo string, artifact string, backendName string) (fileName string, err error)
+	GetPublicSchema(algo string) (data *models.PublicSchema, err error)
+	RegisterKey(in *models.KeyRequest) (out *models.KeyResponse, err error)
+	EnrollCommitment(in *models.CommitmentRequest) (out *models.CommitmentResponse, err error)
+	IssueCredential(in *models.CredentialRequest) (out *models.Credential, err error)
+	GetMembershipRoot() (data *models.MembershipRoot, err error)
+	IsReady() bool
 	PaymentTransaction(in *models.PaymentTransactionRequest) (id string, err error)
 }
 
@@ -65,11 +85,53 @@ type DbRepository interface {
 	GetUserByReferenceNo(referenceNo string) (data *models.Partners, err error)
 	InsertUser(input *models.Partners) (id string, err error)
 	InsertPayment(input *models.Payment) (id string, err error)
+	UpdateCustomerCommitment(id string, commitment string, salt string) (err error)
+	GetCustomerKey(customerId string) (data *models.CustomerKey, err error)
+	SaveCustomerKey(input *models.CustomerKey) (id string, err error)
+	InsertProofJob(input *models.ProofJob) (id string, err error)
+	GetProofJob(id string) (data *models.ProofJob, err error)
+	ClaimProofJob(id string) (claimed bool, err error)
+	FinishProofJob(id string, status string, hash string, message string) (err error)
+	RequeueProofJobs() (ids []string, err error)
+	SpendNullifier(input *models.SpentNullifier) (recorded bool, err error)
+	RevokeNullifier(input *models.SpentNullifier) (err error)
+	IsNullifierRevoked(circuit string, nullifier string) (revoked bool, err error)
+	GetCustomersChangedSince(since time.Time) (data []*models.Customer, err error)
+	CountCustomers() (count int64, err error)
 }
 
 type RedisRepository interface {
-	Set(key string, data string) (err error)
+	Set(key string, data string, ttl time.Duration) (err error)
 	Get(key string) (val string, err error)
+	Del(keys ...string) (err error)
+}
+
+type KeyRepository interface {
+	GetKeys(algo string, b backend.ID) (keys *models2.Keys, err error)
+	IsReady() bool
+}
+
+// MembershipRepository keeps the merkle tree of customer commitments proved by the
+// membership circuit, customers keep their leaf for the life of the tree
+type MembershipRepository interface {
+	// Rebuild replaces the tree with one over every customer, soft deleted ones included
+	Rebuild(curve ecc.ID, depth int, customers []*models.Customer) (err error)
+	// Apply updates the leaves of the changed customers and appends the new ones
+	Apply(customers []*models.Customer) (err error)
+	// Since returns the latest change applied, rows changed from then on are due
+	Since() time.Time
+	// Customers returns how many customers hold a leaf, soft deleted ones included
+	Customers() int
+	Path(customerId string) (path *models2.MerklePath, err error)
+	Root() (data *models.MembershipRoot, err error)
+	// IsRoot reports whether the root is the current one or one of the recent ones
+	IsRoot(root *big.Int) bool
+}
+
+// JobQueue hands the ids of persisted proof jobs over to the workers running them
+type JobQueue interface {
+	Enqueue(id string) (err error)
+	Start(run func(id string))
 }
 
 func (u *Usecase) DoLogin(input *models.LoginRequest) (out *models.LoginResponse, err error) {
@@ -187,331 +249,6 @@ func (u *Usecase) TokenHMAC(input *models.TokenRequest) (out string, err error)
 	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
 }
 
-func (u *Usecase) GetEllipticProof(id string) (data *models.ProofResponse, err error) {
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	pk := groth16.NewProvingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csEllipticPath)
-	internal.Deserialize(pk, internal.PkEllipticPath)
-
-	cData, err := u.db.GetCustomerData(id)
-	if err != nil {
-		return nil, err
-	}
-
-	assignment := &models2.EllipticCurve{}
-	x := len(cData.Name)
-	assignment.X = frontend.Variable(x)
-	assignment.Y = frontend.Variable(x*x*x + x + 5)
-
-	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
-	if err != nil {
-		return
-	}
-
-	proof, err := groth16.Prove(ccs, pk, witness)
-	if err != nil {
-		return
-	}
-
-	var proofBuf bytes.Buffer
-	proof.WriteTo(&proofBuf)
-
-	publicWitness, _ := witness.Public()
-	dataBin, _ := publicWitness.MarshalBinary()
-	dataResponse := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s||%s", string(dataBin), cData.Id)))
-
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "proof"), proofBuf.String())
-	if err != nil {
-		return nil, err
-	}
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "witness"), string(dataBin))
-	if err != nil {
-		return nil, err
-	}
-	data = &models.ProofResponse{Hash: dataResponse}
-
-	return
-}
-
-func (u *Usecase) GetHashProof(id string) (data *models.ProofResponse, err error) {
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	pk := groth16.NewProvingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csPath)
-	internal.Deserialize(pk, internal.PkPath)
-
-	cData, err := u.db.GetCustomerData(id)
-	if err != nil {
-		return nil, err
-	}
-	//marshalData := []byte(internal.StringWithCharset(len(cData.Id), cData.Id))
-	marshalData, _ := json.Marshal(internal.StringWithCharset(len(cData.Id), cData.Id))
-
-	assignment := &models2.Circuit{}
-	b := make([]byte, 32)
-	copy(b, marshalData)
-	hash := internal.MimcHash(b)
-
-	assignment.Secret = frontend.Variable(b)
-	assignment.Hash = frontend.Variable(hash)
-
-	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
-	if err != nil {
-		return
-	}
-
-	proof, err := groth16.Prove(ccs, pk, witness)
-	if err != nil {
-		return
-	}
-
-	var proofBuf bytes.Buffer
-	proof.WriteTo(&proofBuf)
-
-	publicWitness, _ := witness.Public()
-	dataBin, _ := publicWitness.MarshalBinary()
-	dataResponse := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s||%s", string(dataBin), cData.Id)))
-
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "proof"), proofBuf.String())
-	if err != nil {
-		return nil, err
-	}
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "witness"), string(dataBin))
-	if err != nil {
-		return nil, err
-	}
-	data = &models.ProofResponse{Hash: dataResponse}
-
-	return
-}
-
-func (u *Usecase) GetEddsaProof(id string) (data *models.ProofResponse, err error) {
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	pk := groth16.NewProvingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csEddsaPath)
-	internal.Deserialize(pk, internal.PkEddsaPath)
-
-	cData, err := u.db.GetCustomerData(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// instantiate hash function
-	f := bn254.NewMiMC()
-
-	// create a eddsa key pair
-	privateKey, err := eddsa2.New(tedwards.BN254, rand.Reader)
-	publicKey := privateKey.Public()
-
-	marshalData, _ := json.Marshal(internal.StringWithCharset(len(cData.Id), cData.Id))
-	b := make([]byte, 32)
-	copy(b, marshalData)
-
-	// sign the message
-	signature, err := privateKey.Sign(b, f)
-
-	// verifies signature
-	isValid, err := publicKey.Verify(signature, b, f)
-	if !isValid {
-		return nil, errors.New("not valid")
-	}
-	// declare the witness
-	assignment := &models2.EddsaCircuit{}
-
-	// assign message value
-	assignment.Message = b
-
-	// public key bytes
-	_publicKey := publicKey.Bytes()
-
-	// assign public key values
-	assignment.PublicKey.Assign(tedwards.BN254, _publicKey[:32])
-
-	// assign signature values
-	assignment.Signature.Assign(tedwards.BN254, signature)
-
-	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
-	if err != nil {
-		return
-	}
-
-	proof, err := groth16.Prove(ccs, pk, witness)
-	if err != nil {
-		return
-	}
-
-	var proofBuf bytes.Buffer
-	proof.WriteTo(&proofBuf)
-
-	publicWitness, _ := witness.Public()
-	dataBin, _ := publicWitness.MarshalBinary()
-	dataResponse := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s||%s", string(dataBin), cData.Id)))
-
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "proof"), proofBuf.String())
-	if err != nil {
-		return nil, err
-	}
-	err = u.redis.Set(fmt.Sprintf("%s_%s", dataResponse, "witness"), string(dataBin))
-	if err != nil {
-		return nil, err
-	}
-	data = &models.ProofResponse{Hash: dataResponse}
-
-	return
-}
-
-func (u *Usecase) VerifyEllipticProof(code string) (string, bool) {
-	decodeString, err := base64.StdEncoding.DecodeString(code)
-	if err != nil {
-		return "", false
-	}
-	if !strings.Contains(string(decodeString), "||") {
-		return "", false
-	}
-	decodeArray := strings.Split(string(decodeString), "||")
-	cData, err := u.db.GetCustomerData(decodeArray[1])
-	if err != nil {
-		return "", false
-	}
-	if cData.Id == "" {
-		return "", false
-	}
-
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	vk := groth16.NewVerifyingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csEllipticPath)
-	internal.Deserialize(vk, internal.VkEllipticPath)
-
-	// get proof
-	val, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "proof"))
-	if err != nil {
-		return "", false
-	}
-	valReader := strings.NewReader(val)
-	proof := groth16.NewProof(ecc.BN254)
-	proof.ReadFrom(valReader)
-
-	// get witness
-	public, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "witness"))
-	if err != nil {
-		return "", false
-	}
-	witness, _ := witness2.New(ecc.BN254.ScalarField())
-	err = witness.UnmarshalBinary([]byte(public))
-	if err != nil {
-		return "", false
-	}
-	// verify the proof using witness
-	err = groth16.Verify(proof, vk, witness)
-	if err != nil {
-		return "", false
-	}
-	return cData.Id, true
-}
-
-func (u *Usecase) VerifyHashProof(code string) (string, bool) {
-	decodeString, err := base64.StdEncoding.DecodeString(code)
-	if err != nil {
-		return "", false
-	}
-	if !strings.Contains(string(decodeString), "||") {
-		return "", false
-	}
-	decodeArray := strings.Split(string(decodeString), "||")
-	cData, err := u.db.GetCustomerData(decodeArray[1])
-	if err != nil {
-		return "", false
-	}
-	if cData.Id == "" {
-		return "", false
-	}
-
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	vk := groth16.NewVerifyingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csPath)
-	internal.Deserialize(vk, internal.VkPath)
-
-	// get proof
-	val, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "proof"))
-	if err != nil {
-		return "", false
-	}
-	valReader := strings.NewReader(val)
-	proof := groth16.NewProof(ecc.BN254)
-	proof.ReadFrom(valReader)
-
-	// get witness
-	public, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "witness"))
-	if err != nil {
-		return "", false
-	}
-	witness, _ := witness2.New(ecc.BN254.ScalarField())
-	err = witness.UnmarshalBinary([]byte(public))
-	if err != nil {
-		return "", false
-	}
-	// verify the proof using witness
-	err = groth16.Verify(proof, vk, witness)
-	if err != nil {
-		return "", false
-	}
-	return cData.Id, true
-}
-
-func (u *Usecase) VerifyEddsaProof(code string) (string, bool) {
-	decodeString, err := base64.StdEncoding.DecodeString(code)
-	if err != nil {
-		return "", false
-	}
-	if !strings.Contains(string(decodeString), "||") {
-		return "", false
-	}
-	decodeArray := strings.Split(string(decodeString), "||")
-	cData, err := u.db.GetCustomerData(decodeArray[1])
-	if err != nil {
-		return "", false
-	}
-	if cData.Id == "" {
-		return "", false
-	}
-
-	// read R1CS, proving key and verifying keys
-	ccs := groth16.NewCS(ecc.BN254)
-	vk := groth16.NewVerifyingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csEddsaPath)
-	internal.Deserialize(vk, internal.VkEddsaPath)
-
-	// get proof
-	val, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "proof"))
-	if err != nil {
-		return "", false
-	}
-	valReader := strings.NewReader(val)
-	proof := groth16.NewProof(ecc.BN254)
-	proof.ReadFrom(valReader)
-
-	// get witness
-	public, err := u.redis.Get(fmt.Sprintf("%s_%s", code, "witness"))
-	if err != nil {
-		return "", false
-	}
-	witness, _ := witness2.New(ecc.BN254.ScalarField())
-	err = witness.UnmarshalBinary([]byte(public))
-	if err != nil {
-		return "", false
-	}
-	// verify the proof using witness
-	err = groth16.Verify(proof, vk, witness)
-	if err != nil {
-		return "", false
-	}
-	return cData.Id, true
-}
-
 func (u *Usecase) PaymentTransaction(in *models.PaymentTransactionRequest) (id string, err error) {
 	data := &models.Customer{}
 	if in.CustomerNumber != "" {
@@ -0,0 +1,380 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
+	"math/big"
+	"path/filepath"
+	"smart-contract-service/configuration"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDb keeps the rows the usecase reads and writes in memory
+type fakeDb struct {
+	mu         sync.Mutex
+	customers  map[string]*models.Customer
+	partners   map[string]*models.Partners
+	keys       map[string]*models.CustomerKey
+	jobs       map[string]*models.ProofJob
+	nullifiers map[string]*models.SpentNullifier
+
+	customerReads int
+}
+
+func newFakeDb() *fakeDb {
+	birth := time.Date(1990, 2, 3, 0, 0, 0, 0, time.UTC)
+	return &fakeDb{
+		customers: map[string]*models.Customer{
+			"cust-1": {Id: "cust-1", KTP: "3171", NoRek: "123", Name: "Budi", Branch: "JKT", MotherName: "Siti", BirthDate: &birth, Balance: 500000, Seq: 1},
+		},
+		partners: map[string]*models.Partners{
+			"P-1": {Id: "partner-1", ReferenceNo: "P-1", MinAmount: 1000, MaxAmount: 1000000},
+			"P-2": {Id: "partner-2", ReferenceNo: "P-2"},
+		},
+		keys:       make(map[string]*models.CustomerKey),
+		jobs:       make(map[string]*models.ProofJob),
+		nullifiers: make(map[string]*models.SpentNullifier),
+	}
+}
+
+func (f *fakeDb) GetCustomerData(id string) (*models.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.customerReads++
+	if c, ok := f.customers[id]; ok {
+		copied := *c
+		return &copied, nil
+	}
+	return &models.Customer{}, nil
+}
+
+func (f *fakeDb) GetCustomerByAccount(account string) (*models.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.customers {
+		if c.NoRek == account {
+			copied := *c
+			return &copied, nil
+		}
+	}
+	return &models.Customer{}, nil
+}
+
+func (f *fakeDb) GetUserById(id string) (*models.Partners, error) {
+	for _, p := range f.partners {
+		if p.Id == id {
+			return p, nil
+		}
+	}
+	return &models.Partners{}, nil
+}
+
+func (f *fakeDb) GetUserByUsername(username string) (*models.Partners, error) {
+	return &models.Partners{}, nil
+}
+
+func (f *fakeDb) GetUserByReferenceNo(referenceNo string) (*models.Partners, error) {
+	if p, ok := f.partners[referenceNo]; ok {
+		return p, nil
+	}
+	return &models.Partners{}, nil
+}
+
+func (f *fakeDb) InsertUser(input *models.Partners) (string, error) {
+	return input.Id, nil
+}
+
+func (f *fakeDb) InsertPayment(input *models.Payment) (string, error) {
+	return "payment-1", nil
+}
+
+func (f *fakeDb) UpdateCustomerCommitment(id string, commitment string, salt string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[id]
+	if !ok {
+		return ErrCustomerNotFound
+	}
+	c.Commitment, c.CommitmentSalt = commitment, salt
+	return nil
+}
+
+func (f *fakeDb) GetCustomerKey(customerId string) (*models.CustomerKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if k, ok := f.keys[customerId]; ok {
+		return k, nil
+	}
+	return &models.CustomerKey{}, nil
+}
+
+func (f *fakeDb) SaveCustomerKey(input *models.CustomerKey) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[input.CustomerId] = input
+	return input.CustomerId, nil
+}
+
+func (f *fakeDb) InsertProofJob(input *models.ProofJob) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	input.Id = fmt.Sprintf("job-%d", len(f.jobs)+1)
+	copied := *input
+	copied.Status = models.ProofJobQueued
+	f.jobs[input.Id] = &copied
+	return input.Id, nil
+}
+
+func (f *fakeDb) GetProofJob(id string) (*models.ProofJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job, ok := f.jobs[id]; ok {
+		copied := *job
+		return &copied, nil
+	}
+	return &models.ProofJob{}, nil
+}
+
+func (f *fakeDb) ClaimProofJob(id string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job, ok := f.jobs[id]
+	if !ok || job.Status != models.ProofJobQueued {
+		return false, nil
+	}
+	job.Status = models.ProofJobRunning
+	return true, nil
+}
+
+func (f *fakeDb) FinishProofJob(id string, status string, hash string, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if job, ok := f.jobs[id]; ok {
+		job.Status, job.Hash, job.Error = status, hash, message
+	}
+	return nil
+}
+
+func (f *fakeDb) RequeueProofJobs() ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeDb) SpendNullifier(input *models.SpentNullifier) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := input.Circuit + "|" + input.Nullifier
+	if _, ok := f.nullifiers[key]; ok {
+		return false, nil
+	}
+	// the unique index over circuit, customer and context, null contexts never collide
+	for _, spent := range f.nullifiers {
+		if input.Context != nil && spent.Context != nil && *spent.Context == *input.Context &&
+			spent.Circuit == input.Circuit && spent.CustomerId == input.CustomerId {
+			return false, nil
+		}
+	}
+	copied := *input
+	f.nullifiers[key] = &copied
+	return true, nil
+}
+
+func (f *fakeDb) RevokeNullifier(input *models.SpentNullifier) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *input
+	copied.Revoked = true
+	f.nullifiers[input.Circuit+"|"+input.Nullifier] = &copied
+	return nil
+}
+
+func (f *fakeDb) IsNullifierRevoked(circuit string, nullifier string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.nullifiers[circuit+"|"+nullifier]
+	return ok && n.Revoked, nil
+}
+
+func (f *fakeDb) GetCustomersChangedSince(since time.Time) ([]*models.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var changed []*models.Customer
+	for _, c := range f.customers {
+		copied := *c
+		changed = append(changed, &copied)
+	}
+	return changed, nil
+}
+
+func (f *fakeDb) CountCustomers() (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.customers)), nil
+}
+
+// fakeRedis is a map, it never expires keys
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (f *fakeRedis) Set(key string, data string, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.values == nil {
+		f.values = make(map[string]string)
+	}
+	f.values[key] = data
+	return nil
+}
+
+func (f *fakeRedis) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	val, ok := f.values[key]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	return val, nil
+}
+
+func (f *fakeRedis) Del(keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.values, key)
+	}
+	return nil
+}
+
+// fakeKeys runs a development setup of a circuit the first time its keys are asked for
+type fakeKeys struct {
+	mu   sync.Mutex
+	keys map[string]*models2.Keys
+}
+
+func (f *fakeKeys) GetKeys(algo string, b backend.ID) (*models2.Keys, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := algo + "|" + b.String()
+	if keys, ok := f.keys[name]; ok {
+		return keys, nil
+	}
+
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return nil, err
+	}
+	ccs, err := models2.Compile(def.Curve, b, def.Circuit())
+	if err != nil {
+		return nil, err
+	}
+	var srs kzg.SRS
+	if b == backend.PLONK {
+		if srs, err = models2.NewSRS(def.Curve, models2.SRSSize(ccs)); err != nil {
+			return nil, err
+		}
+	}
+	keys, err := models2.Setup(def.Curve, b, ccs, srs)
+	if err != nil {
+		return nil, err
+	}
+	if f.keys == nil {
+		f.keys = make(map[string]*models2.Keys)
+	}
+	f.keys[name] = keys
+	return keys, nil
+}
+
+func (f *fakeKeys) IsReady() bool {
+	return true
+}
+
+// fakeMembers counts the customers it is given, none of them has a path in the tree
+type fakeMembers struct {
+	customers int
+}
+
+func (f *fakeMembers) Rebuild(curve ecc.ID, depth int, customers []*models.Customer) error {
+	f.customers = len(customers)
+	return nil
+}
+
+func (f *fakeMembers) Apply(customers []*models.Customer) error {
+	// the fake database reports every customer as changed
+	if len(customers) > f.customers {
+		f.customers = len(customers)
+	}
+	return nil
+}
+
+func (f *fakeMembers) Since() time.Time {
+	return time.Time{}
+}
+
+func (f *fakeMembers) Customers() int {
+	return f.customers
+}
+
+func (f *fakeMembers) Path(customerId string) (*models2.MerklePath, error) {
+	return nil, models2.ErrNotMember
+}
+
+func (f *fakeMembers) Root() (*models.MembershipRoot, error) {
+	return nil, models2.ErrNotMember
+}
+
+func (f *fakeMembers) IsRoot(root *big.Int) bool {
+	return false
+}
+
+// fakeQueue records the jobs handed over, nothing runs them
+type fakeQueue struct {
+	ids []string
+}
+
+func (f *fakeQueue) Enqueue(id string) error {
+	f.ids = append(f.ids, id)
+	return nil
+}
+
+func (f *fakeQueue) Start(run func(id string)) {}
+
+// keys are shared by every test, a setup of the larger circuits takes seconds
+var testKeys = &fakeKeys{}
+
+func newTestUsecase(t *testing.T) (*Usecase, *fakeDb, *fakeRedis) {
+	t.Helper()
+	cfg := configuration.ConfigApp{
+		Secret:             "test-secret",
+		KeyEncryptionKey:   "test-key-encryption-key",
+		ProofTokenMode:     "redis",
+		ProofTokenLegacy:   true,
+		ProofTokenTTL:      300,
+		IssuerKeyLocation:  filepath.Join(t.TempDir(), "eddsa-issuer.pem"),
+		PublicKeyLocation:  "../../assets/rsa256-public.pem",
+		PrivateKeyLocation: "../../assets/rsa256-private.pem",
+		CredentialMinAge:   17,
+		BatchProofMaxSize:  10,
+		BatchVerifyMaxSize: 10,
+	}
+	db, redis := newFakeDb(), &fakeRedis{}
+	u := NewUsecase(redis, db, testKeys, &fakeQueue{}, &fakeMembers{}, cfg)
+	if _, err := u.EnrollCommitment(&models.CommitmentRequest{CustomerId: "cust-1"}); err != nil {
+		t.Fatal(err)
+	}
+	return u, db, redis
+}
+
+func assertIs(t *testing.T, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected %v, got %v", target, err)
+	}
+}
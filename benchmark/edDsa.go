@@ -18,6 +18,7 @@ import (
 	"github.com/consensys/gnark/std/signature/eddsa"
 	"log"
 	"smart-contract-service/internal"
+	models2 "smart-contract-service/models/circuit"
 	"strings"
 )
 
@@ -44,6 +45,7 @@ func (circuit *EddsaCircuit) Define(api frontend.API) error {
 
 func CreateEddsaCircuit() (err error) {
 	var circuit EddsaCircuit
+	artifact := models2.NewArtifact("eddsa")
 
 	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
 	if err != nil {
@@ -55,17 +57,17 @@ func CreateEddsaCircuit() (err error) {
 		log.Fatal(err)
 	}
 
-	internal.Serialize(r1cs, internal.R1csEddsaPath)
-	internal.Serialize(pk, internal.PkEddsaPath)
-	internal.Serialize(vk, internal.VkEddsaPath)
+	internal.Serialize(r1cs, artifact.R1cs)
+	internal.Serialize(pk, artifact.Pk)
+	internal.Serialize(vk, artifact.Vk)
 
 	// read R1CS, proving key and verifying keys
 	ccs := groth16.NewCS(ecc.BN254)
 	pk = groth16.NewProvingKey(ecc.BN254)
 	vk = groth16.NewVerifyingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csEddsaPath)
-	internal.Deserialize(pk, internal.PkEddsaPath)
-	internal.Deserialize(vk, internal.VkEddsaPath)
+	internal.Deserialize(ccs, artifact.R1cs)
+	internal.Deserialize(pk, artifact.Pk)
+	internal.Deserialize(vk, artifact.Vk)
 
 	// instantiate hash function
 	f := bn254.NewMiMC()
@@ -9,6 +9,7 @@ import (
 	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
 	"log"
 	"smart-contract-service/internal"
+	models2 "smart-contract-service/models/circuit"
 	"strings"
 )
 
@@ -31,6 +32,7 @@ func (circuit *EllipticCurve) Define(api frontend.API) error {
 
 func CreateEllipticCircuit() (err error) {
 	var circuit EllipticCurve
+	artifact := models2.NewArtifact("mimc")
 
 	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
 	if err != nil {
@@ -42,17 +44,17 @@ func CreateEllipticCircuit() (err error) {
 		log.Fatal(err)
 	}
 
-	internal.Serialize(r1cs, internal.R1csPath)
-	internal.Serialize(pk, internal.PkPath)
-	internal.Serialize(vk, internal.VkPath)
+	internal.Serialize(r1cs, artifact.R1cs)
+	internal.Serialize(pk, artifact.Pk)
+	internal.Serialize(vk, artifact.Vk)
 
 	// read R1CS, proving key and verifying keys
 	ccs := groth16.NewCS(ecc.BN254)
 	pk = groth16.NewProvingKey(ecc.BN254)
 	vk = groth16.NewVerifyingKey(ecc.BN254)
-	internal.Deserialize(ccs, internal.R1csPath)
-	internal.Deserialize(pk, internal.PkPath)
-	internal.Deserialize(vk, internal.VkPath)
+	internal.Deserialize(ccs, artifact.R1cs)
+	internal.Deserialize(pk, artifact.Pk)
+	internal.Deserialize(vk, artifact.Vk)
 
 	assignment := &EllipticCurve{
 		X: 3,
@@ -0,0 +1,282 @@
+// Package ceremony runs the groth16 trusted setup as a multi-party computation, so the
+// keys are sound as long as a single contributor destroyed their randomness.
+//
+// Phase 1 computes powers of tau shared by every circuit up to a size, phase 2 derives
+// the circuit specific terms from the compiled R1CS and re-randomizes delta. Every
+// contribution is stored as a file chained to the previous one by its sha256 and carries
+// a proof of knowledge of the contributed secret, so auditors can replay the transcript.
+package ceremony
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"io"
+	"math/big"
+	"os"
+	"runtime"
+	"sync"
+)
+
+var (
+	ErrInvalidContribution = errors.New("invalid ceremony contribution")
+	ErrTranscriptMismatch  = errors.New("transcript does not match its inputs")
+)
+
+// Hash is the sha256 of a serialized contribution, each contribution commits to the previous one
+type Hash [sha256.Size]byte
+
+func (h Hash) String() string {
+	return fmt.Sprintf("%x", h[:])
+}
+
+// PublicKey proves knowledge of a contributed secret x without revealing it
+type PublicKey struct {
+	S, SX curve.G1Affine // [s]1 and [s·x]1 for a random s
+	XR    curve.G2Affine // [x]2 of the challenge point derived from S, SX and the transcript
+}
+
+func newPublicKey(x fr.Element, previous Hash, label string) (pk PublicKey, err error) {
+	var s fr.Element
+	if _, err = s.SetRandom(); err != nil {
+		return
+	}
+	_, _, g1, _ := curve.Generators()
+	pk.S.ScalarMultiplication(&g1, toBig(&s))
+	s.Mul(&s, &x)
+	pk.SX.ScalarMultiplication(&g1, toBig(&s))
+
+	r, err := challenge(pk.S, pk.SX, previous, label)
+	if err != nil {
+		return
+	}
+	pk.XR.ScalarMultiplication(&r, toBig(&x))
+	return pk, nil
+}
+
+// verify checks the proof of knowledge and returns the challenge point R, the
+// contributed secret x is then the ratio between [x]R and R
+func (pk *PublicKey) verify(previous Hash, label string) (curve.G2Affine, error) {
+	r, err := challenge(pk.S, pk.SX, previous, label)
+	if err != nil {
+		return r, err
+	}
+	if pk.S.IsInfinity() || !sameRatio(pk.SX, pk.S, pk.XR, r) {
+		return r, fmt.Errorf("%w : proof of knowledge of %s", ErrInvalidContribution, label)
+	}
+	return r, nil
+}
+
+func challenge(s, sx curve.G1Affine, previous Hash, label string) (curve.G2Affine, error) {
+	sBytes, sxBytes := s.Bytes(), sx.Bytes()
+	msg := make([]byte, 0, len(sBytes)+len(sxBytes)+len(previous))
+	msg = append(append(append(msg, sBytes[:]...), sxBytes[:]...), previous[:]...)
+	return curve.HashToG2(msg, []byte("SMART-CONTRACT-SERVICE-CEREMONY-"+label))
+}
+
+// sameRatio reports whether a1/b1 in G1 equals a2/b2 in G2, that is e(a1, b2) == e(b1, a2)
+func sameRatio(a1, b1 curve.G1Affine, a2, b2 curve.G2Affine) bool {
+	var nb1 curve.G1Affine
+	nb1.Neg(&b1)
+	ok, err := curve.PairingCheck([]curve.G1Affine{a1, nb1}, []curve.G2Affine{b2, a2})
+	return err == nil && ok
+}
+
+// randomScalars samples the coefficients of the random linear combinations a verifier
+// checks many points with in a single pairing
+func randomScalars(n int) ([]fr.Element, error) {
+	r := make([]fr.Element, n)
+	for i := range r {
+		if _, err := r[i].SetRandom(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// combineG1 returns Σ r_i·P_i
+func combineG1(points []curve.G1Affine, r []fr.Element) (res curve.G1Affine, err error) {
+	_, err = res.MultiExp(points, r[:len(points)], ecc.MultiExpConfig{})
+	return
+}
+
+// linearCombinationG1 returns Σ r_i·P_i and Σ r_i·P_i+1, which have the ratio of two
+// consecutive points when the points are successive powers
+func linearCombinationG1(points []curve.G1Affine, r []fr.Element) (l, shifted curve.G1Affine, err error) {
+	n := len(points) - 1
+	if l, err = combineG1(points[:n], r); err != nil {
+		return
+	}
+	shifted, err = combineG1(points[1:], r)
+	return
+}
+
+func linearCombinationG2(points []curve.G2Affine, r []fr.Element) (l, shifted curve.G2Affine, err error) {
+	n := len(points) - 1
+	config := ecc.MultiExpConfig{}
+	if _, err = l.MultiExp(points[:n], r[:n], config); err != nil {
+		return
+	}
+	_, err = shifted.MultiExp(points[1:], r[:n], config)
+	return
+}
+
+// scaleG1 multiplies each point by its scalar in place
+func scaleG1(points []curve.G1Affine, scalars []fr.Element) {
+	parallelize(len(points), func(start, end int) {
+		var b big.Int
+		for i := start; i < end; i++ {
+			points[i].ScalarMultiplication(&points[i], scalars[i].BigInt(&b))
+		}
+	})
+}
+
+func scaleG2(points []curve.G2Affine, scalars []fr.Element) {
+	parallelize(len(points), func(start, end int) {
+		var b big.Int
+		for i := start; i < end; i++ {
+			points[i].ScalarMultiplication(&points[i], scalars[i].BigInt(&b))
+		}
+	})
+}
+
+// powers returns 1, x, x², ... x^(n-1)
+func powers(x fr.Element, n int) []fr.Element {
+	p := make([]fr.Element, n)
+	p[0].SetOne()
+	for i := 1; i < n; i++ {
+		p[i].Mul(&p[i-1], &x)
+	}
+	return p
+}
+
+// nonZeroRandom samples a contribution secret
+func nonZeroRandom() (x fr.Element, err error) {
+	for x.IsZero() {
+		if _, err = x.SetRandom(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func toBig(x *fr.Element) *big.Int {
+	var b big.Int
+	return x.BigInt(&b)
+}
+
+// parallelize splits n iterations over the available cpus
+func parallelize(n int, work func(start, end int)) {
+	nbTasks := runtime.NumCPU()
+	if nbTasks > n {
+		nbTasks = n
+	}
+	if nbTasks <= 1 {
+		work(0, n)
+		return
+	}
+	size := (n + nbTasks - 1) / nbTasks
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			work(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// writeName encodes the contributor name, length prefixed
+func writeName(enc *curve.Encoder, w io.Writer, name string) error {
+	if err := enc.Encode(uint32(len(name))); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(name))
+	return err
+}
+
+func readName(dec *curve.Decoder, r io.Reader) (string, error) {
+	var n uint32
+	if err := dec.Decode(&n); err != nil {
+		return "", err
+	}
+	if n > 1<<10 {
+		return "", fmt.Errorf("%w : contributor name of %d bytes", ErrInvalidContribution, n)
+	}
+	name := make([]byte, n)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// writeFile stores a serialized object and returns its hash
+func writeFile(object io.WriterTo, fileName string) (Hash, error) {
+	var buf bytes.Buffer
+	if _, err := object.WriteTo(&buf); err != nil {
+		return Hash{}, err
+	}
+	if err := os.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+		return Hash{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// readFile decodes a serialized object, the whole file must be consumed
+func readFile(object io.ReaderFrom, fileName string) (Hash, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return Hash{}, err
+	}
+	n, err := object.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return Hash{}, fmt.Errorf("read %s: %w", fileName, err)
+	}
+	if n != int64(len(data)) {
+		return Hash{}, fmt.Errorf("read %s: %d of %d bytes decoded: %w", fileName, n, len(data), ErrInvalidContribution)
+	}
+	return sha256.Sum256(data), nil
+}
+
+// hashOf returns the hash an object has once serialized
+func hashOf(object io.WriterTo) (Hash, error) {
+	h := sha256.New()
+	if _, err := object.WriteTo(h); err != nil {
+		return Hash{}, err
+	}
+	var sum Hash
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// countingWriter lets the encoders and raw writes of a file report one size
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,187 @@
+package ceremony
+
+import (
+	"errors"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"io"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+// cubicCircuit proves knowledge of x such that x³ + x + 5 = y
+type cubicCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+var cubic = &models2.Definition{
+	Name:    "cubic",
+	Circuit: func() frontend.Circuit { return &cubicCircuit{} },
+	Curve:   ecc.BN254,
+	Backend: backend.GROTH16,
+}
+
+// contributed returns a transcript of the cubic circuit with two contributions in each phase
+func contributed(t *testing.T) *Transcript {
+	t.Helper()
+	transcript := NewTranscript(t.TempDir())
+	if _, err := transcript.InitPhase1(4); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := transcript.ContributePhase1(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := transcript.InitPhase2(cubic); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := transcript.Contribute(cubic, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return transcript
+}
+
+func TestCeremony(t *testing.T) {
+	transcript := contributed(t)
+
+	records, _, err := transcript.VerifyPhase1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2].Name != "bob" {
+		t.Fatalf("phase 1 records %+v, expected the initial one then alice and bob", records)
+	}
+	records, _, _, err = transcript.Verify(cubic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1].Name != "alice" {
+		t.Fatalf("phase 2 records %+v, expected the initial one then alice and bob", records)
+	}
+
+	keys, last, err := transcript.Finalize(cubic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != records[2] {
+		t.Fatalf("keys come from %+v, expected the last contribution %+v", last, records[2])
+	}
+
+	full, err := frontend.NewWitness(&cubicCircuit{X: 3, Y: 35}, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := keys.Prove(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = keys.Verify(proof, public); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := frontend.NewWitness(&cubicCircuit{Y: 36}, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = keys.Verify(proof, other); err == nil {
+		t.Fatal("proof verified against another public input")
+	}
+}
+
+func TestCeremonyRefusesNoContribution(t *testing.T) {
+	transcript := NewTranscript(t.TempDir())
+	if _, err := transcript.InitPhase1(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transcript.InitPhase2(cubic); err == nil {
+		t.Fatal("phase 2 derived from a phase 1 without contribution")
+	}
+	if _, err := transcript.ContributePhase1("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transcript.InitPhase2(cubic); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := transcript.Finalize(cubic); err == nil {
+		t.Fatal("keys finalized without a phase 2 contribution")
+	}
+}
+
+func TestCeremonyTamperedContribution(t *testing.T) {
+	t.Run("phase 1", func(t *testing.T) {
+		transcript := contributed(t)
+		fileName := transcript.file(transcript.phase1Dir(), 2, phase1Ext)
+		p := &Phase1{}
+		tamper(t, p, fileName, func() {
+			p.G1.Tau[2] = p.G1.Tau[3]
+		})
+
+		if _, _, err := transcript.VerifyPhase1(); !errors.Is(err, ErrInvalidContribution) {
+			t.Fatalf("tampered phase 1 verified with %v", err)
+		}
+		if _, _, err := transcript.Finalize(cubic); err == nil {
+			t.Fatal("keys finalized on a tampered phase 1")
+		}
+	})
+
+	t.Run("phase 2", func(t *testing.T) {
+		transcript := contributed(t)
+		fileName := transcript.file(transcript.circuitDir(cubic), 2, phase2Ext)
+		p := &Phase2{}
+		tamper(t, p, fileName, func() {
+			p.G1.PK[0] = p.G1.Delta
+		})
+
+		if _, _, _, err := transcript.Verify(cubic); !errors.Is(err, ErrInvalidContribution) {
+			t.Fatalf("tampered phase 2 verified with %v", err)
+		}
+		if _, _, err := transcript.Finalize(cubic); err == nil {
+			t.Fatal("keys finalized on a tampered phase 2")
+		}
+	})
+
+	t.Run("delta without proof of knowledge", func(t *testing.T) {
+		transcript := contributed(t)
+		fileName := transcript.file(transcript.circuitDir(cubic), 2, phase2Ext)
+		p := &Phase2{}
+		tamper(t, p, fileName, func() {
+			p.PublicKey.SX = p.PublicKey.S
+		})
+
+		if _, _, _, err := transcript.Verify(cubic); !errors.Is(err, ErrInvalidContribution) {
+			t.Fatalf("contribution without proof of knowledge verified with %v", err)
+		}
+	})
+}
+
+// tamper rewrites a contribution of the transcript after change altered it in place
+func tamper(t *testing.T, contribution contributionFile, fileName string, change func()) {
+	t.Helper()
+	if _, err := readFile(contribution, fileName); err != nil {
+		t.Fatal(err)
+	}
+	change()
+	if _, err := writeFile(contribution, fileName); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type contributionFile interface {
+	io.ReaderFrom
+	io.WriterTo
+}
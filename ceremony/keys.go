@@ -0,0 +1,93 @@
+package ceremony
+
+import (
+	"bytes"
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16"
+	cs "github.com/consensys/gnark/constraint/bn254"
+)
+
+// NewKeys assembles the groth16 keys of the circuit from its evaluations and the last
+// phase 2 contribution. The keys are encoded the way gnark serializes them and read
+// back, gnark does not export the key types.
+func NewKeys(r1cs *cs.R1CS, eval *Evaluations, p2 *Phase2) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	_, _, _, g2 := curve.Generators()
+
+	// the prover skips the wires whose A or B is the point at infinity
+	nbWires := len(eval.G1.A)
+	infinityA := make([]bool, nbWires)
+	infinityB := make([]bool, nbWires)
+	var a, b []curve.G1Affine
+	var bG2 []curve.G2Affine
+	for i := 0; i < nbWires; i++ {
+		if eval.G1.A[i].IsInfinity() {
+			infinityA[i] = true
+		} else {
+			a = append(a, eval.G1.A[i])
+		}
+		if eval.G1.B[i].IsInfinity() {
+			infinityB[i] = true
+		} else {
+			b = append(b, eval.G1.B[i])
+			bG2 = append(bG2, eval.G2.B[i])
+		}
+	}
+
+	var pkBuf bytes.Buffer
+	domain := fft.NewDomain(uint64(len(r1cs.Constraints)))
+	if _, err := domain.WriteTo(&pkBuf); err != nil {
+		return nil, nil, err
+	}
+	enc := curve.NewEncoder(&pkBuf)
+	toEncode := []interface{}{
+		&eval.G1.Alpha,
+		&eval.G1.Beta,
+		&p2.G1.Delta,
+		a,
+		b,
+		p2.G1.Z,
+		p2.G1.PK,
+		&eval.G2.Beta,
+		&p2.G2.Delta,
+		bG2,
+		uint64(nbWires),
+		uint64(nbWires - len(a)),
+		uint64(nbWires - len(b)),
+		infinityA,
+		infinityB,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var vkBuf bytes.Buffer
+	enc = curve.NewEncoder(&vkBuf)
+	toEncode = []interface{}{
+		&eval.G1.Alpha,
+		&eval.G1.Beta,
+		&eval.G2.Beta,
+		&g2, // gamma
+		&p2.G1.Delta,
+		&p2.G2.Delta,
+		eval.G1.VK,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pk := groth16.NewProvingKey(ecc.BN254)
+	if _, err := pk.ReadFrom(&pkBuf); err != nil {
+		return nil, nil, err
+	}
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	if _, err := vk.ReadFrom(&vkBuf); err != nil {
+		return nil, nil, err
+	}
+	return pk, vk, nil
+}
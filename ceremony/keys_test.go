@@ -0,0 +1,101 @@
+package ceremony
+
+import (
+	"bytes"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"math/bits"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+	"testing"
+)
+
+// TestNewKeysRegisteredCircuit pins the key encoding NewKeys writes by hand to the one
+// gnark reads, with a circuit the service proves
+func TestNewKeysRegisteredCircuit(t *testing.T) {
+	def, err := models2.Lookup(models2.HashAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1cs, err := compile(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	power := bits.TrailingZeros64(ecc.NextPowerOfTwo(uint64(len(r1cs.Constraints))))
+
+	transcript := NewTranscript(t.TempDir())
+	if _, err = transcript.InitPhase1(power); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = transcript.ContributePhase1("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = transcript.InitPhase2(def); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = transcript.Contribute(def, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	keys, _, err := transcript.Finalize(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys are read back the way the service loads them from their artifacts
+	pk, vk := groth16.NewProvingKey(ecc.BN254), groth16.NewVerifyingKey(ecc.BN254)
+	var buf bytes.Buffer
+	if _, err = keys.Pk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pk.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if _, err = keys.Vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = vk.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	salt, err := models2.GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := &models.Customer{Id: "cust-1", KTP: "3171", NoRek: "123", MotherName: "Siti", CommitmentSalt: salt}
+	if customer.Commitment, err = models2.Commitment(models2.CommitmentCurve, customer, salt); err != nil {
+		t.Fatal(err)
+	}
+	assignment, err := def.Assign(&models2.WitnessInput{Customer: customer, Curve: def.Curve, Context: "ref-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(assignment, def.Curve.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(r1cs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = groth16.Verify(proof, vk, public); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := def.Assign(&models2.WitnessInput{Customer: customer, Curve: def.Curve, Context: "ref-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, err := frontend.NewWitness(other, def.Curve.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = groth16.Verify(proof, vk, otherPublic); err == nil {
+		t.Fatal("proof verified for another context")
+	}
+}
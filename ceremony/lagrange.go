@@ -0,0 +1,153 @@
+package ceremony
+
+import (
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"math/big"
+	"math/bits"
+)
+
+// The lagrange basis L_i(X) = 1/N·Σ_k ω^(-ik)·X^k of the domain is an inverse fft of the
+// monomials, so [L_i(τ)] is computed from the powers of tau with an fft in the exponent.
+
+// inverseTwiddles returns ω^-k for k < N/2
+func inverseTwiddles(domain *fft.Domain) []big.Int {
+	n := int(domain.Cardinality)
+	w := powers(domain.GeneratorInv, n/2)
+	twiddles := make([]big.Int, len(w))
+	for i := range w {
+		w[i].BigInt(&twiddles[i])
+	}
+	return twiddles
+}
+
+// lagrangeG1 returns [L_i(τ)]1 for i < N from [τ^i]1 for i < N
+func lagrangeG1(monomials []curve.G1Affine, domain *fft.Domain) []curve.G1Affine {
+	n := int(domain.Cardinality)
+	a := make([]curve.G1Jac, n)
+	for i := range a {
+		a[bitReverse(i, n)].FromAffine(&monomials[i])
+	}
+	twiddles := inverseTwiddles(domain)
+	for m := 2; m <= n; m <<= 1 {
+		half, stride := m/2, n/m
+		parallelize(n/2, func(start, end int) {
+			var t curve.G1Jac
+			for b := start; b < end; b++ {
+				// butterfly b of the stage works on j within block k
+				k, j := (b/half)*m, b%half
+				u, v := &a[k+j], &a[k+j+half]
+				if j == 0 {
+					t.Set(v)
+				} else {
+					t.ScalarMultiplication(v, &twiddles[j*stride])
+				}
+				v.Set(u).SubAssign(&t)
+				u.AddAssign(&t)
+			}
+		})
+	}
+
+	var nInv big.Int
+	domain.CardinalityInv.BigInt(&nInv)
+	parallelize(n, func(start, end int) {
+		for i := start; i < end; i++ {
+			a[i].ScalarMultiplication(&a[i], &nInv)
+		}
+	})
+	return curve.BatchJacobianToAffineG1(a)
+}
+
+// lagrangeG2 returns [L_i(τ)]2 for i < N from [τ^i]2 for i < N
+func lagrangeG2(monomials []curve.G2Affine, domain *fft.Domain) []curve.G2Affine {
+	n := int(domain.Cardinality)
+	a := make([]curve.G2Jac, n)
+	for i := range a {
+		a[bitReverse(i, n)].FromAffine(&monomials[i])
+	}
+	twiddles := inverseTwiddles(domain)
+	for m := 2; m <= n; m <<= 1 {
+		half, stride := m/2, n/m
+		parallelize(n/2, func(start, end int) {
+			var t curve.G2Jac
+			for b := start; b < end; b++ {
+				k, j := (b/half)*m, b%half
+				u, v := &a[k+j], &a[k+j+half]
+				if j == 0 {
+					t.Set(v)
+				} else {
+					t.ScalarMultiplication(v, &twiddles[j*stride])
+				}
+				v.Set(u).SubAssign(&t)
+				u.AddAssign(&t)
+			}
+		})
+	}
+
+	var nInv big.Int
+	domain.CardinalityInv.BigInt(&nInv)
+	points := make([]curve.G2Affine, n)
+	parallelize(n, func(start, end int) {
+		for i := start; i < end; i++ {
+			a[i].ScalarMultiplication(&a[i], &nInv)
+			points[i].FromJacobian(&a[i])
+		}
+	})
+	return points
+}
+
+func bitReverse(i, n int) int {
+	return int(bits.Reverse64(uint64(i)) >> (64 - bits.TrailingZeros64(uint64(n))))
+}
+
+// bitReverseG1 permutes the points the way the groth16 prover expects the Z query
+func bitReverseG1(points []curve.G1Affine) {
+	n := len(points)
+	for i := range points {
+		if j := bitReverse(i, n); i < j {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+}
+
+// scalar multiplications by the small coefficients of the R1CS are cheap additions
+func accumulateG1(res *curve.G1Jac, p *curve.G1Affine, coeff *fr.Element) {
+	var one, minusOne fr.Element
+	one.SetOne()
+	minusOne.Neg(&one)
+	switch {
+	case coeff.IsZero():
+	case coeff.Equal(&one):
+		res.AddMixed(p)
+	case coeff.Equal(&minusOne):
+		var neg curve.G1Affine
+		neg.Neg(p)
+		res.AddMixed(&neg)
+	default:
+		var t curve.G1Jac
+		t.FromAffine(p)
+		t.ScalarMultiplication(&t, toBig(coeff))
+		res.AddAssign(&t)
+	}
+}
+
+func accumulateG2(res *curve.G2Jac, p *curve.G2Affine, coeff *fr.Element) {
+	var one, minusOne fr.Element
+	one.SetOne()
+	minusOne.Neg(&one)
+	switch {
+	case coeff.IsZero():
+	case coeff.Equal(&one):
+		res.AddMixed(p)
+	case coeff.Equal(&minusOne):
+		var neg curve.G2Affine
+		neg.Neg(p)
+		res.AddMixed(&neg)
+	default:
+		var t curve.G2Jac
+		t.FromAffine(p)
+		t.ScalarMultiplication(&t, toBig(coeff))
+		res.AddAssign(&t)
+	}
+}
@@ -0,0 +1,247 @@
+package ceremony
+
+import (
+	"fmt"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"io"
+)
+
+// Phase1 is a contribution to the powers of tau, for circuits of up to N constraints
+// where N is the number of G2 powers
+type Phase1 struct {
+	Name     string // contributor, informative only
+	Previous Hash   // hash of the previous contribution, zero for the initial parameters
+
+	// proofs of knowledge of the contributed tau, alpha and beta
+	PublicKeys struct {
+		Tau, Alpha, Beta PublicKey
+	}
+
+	// [τ^i]1 for i < 2N, [ατ^i]1 and [βτ^i]1 for i < N
+	G1 struct {
+		Tau, AlphaTau, BetaTau []curve.G1Affine
+	}
+	// [τ^i]2 for i < N and [β]2
+	G2 struct {
+		Tau  []curve.G2Affine
+		Beta curve.G2Affine
+	}
+}
+
+// NewPhase1 returns the initial parameters, every secret set to one
+func NewPhase1(power int) *Phase1 {
+	n := 1 << power
+	_, _, g1, g2 := curve.Generators()
+	p := &Phase1{}
+	p.G1.Tau = fill(g1, 2*n)
+	p.G1.AlphaTau = fill(g1, n)
+	p.G1.BetaTau = fill(g1, n)
+	p.G2.Tau = make([]curve.G2Affine, n)
+	for i := range p.G2.Tau {
+		p.G2.Tau[i] = g2
+	}
+	p.G2.Beta = g2
+	return p
+}
+
+// N is the largest number of constraints the parameters set up
+func (p *Phase1) N() int {
+	return len(p.G2.Tau)
+}
+
+// Contribute returns the parameters updated with fresh secrets, the secrets are
+// dropped once the points are multiplied
+func (p *Phase1) Contribute(previous Hash, name string) (*Phase1, error) {
+	var tau, alpha, beta fr.Element
+	var err error
+	for _, x := range []*fr.Element{&tau, &alpha, &beta} {
+		if *x, err = nonZeroRandom(); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		tau.SetZero()
+		alpha.SetZero()
+		beta.SetZero()
+	}()
+
+	next := &Phase1{Name: name, Previous: previous}
+	if next.PublicKeys.Tau, err = newPublicKey(tau, previous, "tau"); err != nil {
+		return nil, err
+	}
+	if next.PublicKeys.Alpha, err = newPublicKey(alpha, previous, "alpha"); err != nil {
+		return nil, err
+	}
+	if next.PublicKeys.Beta, err = newPublicKey(beta, previous, "beta"); err != nil {
+		return nil, err
+	}
+
+	n := p.N()
+	tauPowers := powers(tau, 2*n)
+	alphaTau := make([]fr.Element, n)
+	betaTau := make([]fr.Element, n)
+	for i := 0; i < n; i++ {
+		alphaTau[i].Mul(&tauPowers[i], &alpha)
+		betaTau[i].Mul(&tauPowers[i], &beta)
+	}
+
+	next.G1.Tau = append([]curve.G1Affine{}, p.G1.Tau...)
+	next.G1.AlphaTau = append([]curve.G1Affine{}, p.G1.AlphaTau...)
+	next.G1.BetaTau = append([]curve.G1Affine{}, p.G1.BetaTau...)
+	next.G2.Tau = append([]curve.G2Affine{}, p.G2.Tau...)
+	scaleG1(next.G1.Tau, tauPowers)
+	scaleG1(next.G1.AlphaTau, alphaTau)
+	scaleG1(next.G1.BetaTau, betaTau)
+	scaleG2(next.G2.Tau, tauPowers[:n])
+	next.G2.Beta.ScalarMultiplication(&p.G2.Beta, toBig(&beta))
+
+	for _, s := range [][]fr.Element{tauPowers, alphaTau, betaTau} {
+		for i := range s {
+			s[i].SetZero()
+		}
+	}
+	return next, nil
+}
+
+// Verify checks next is a contribution on top of p, whose hash is previous
+func (p *Phase1) Verify(next *Phase1, previous Hash) error {
+	if next.Previous != previous {
+		return fmt.Errorf("%w : previous hash %s, expected %s", ErrInvalidContribution, next.Previous, previous)
+	}
+	if len(next.G1.Tau) != len(p.G1.Tau) || next.N() != p.N() {
+		return fmt.Errorf("%w : size changed", ErrInvalidContribution)
+	}
+
+	// the new secrets are known to the contributor and multiply the previous ones
+	rTau, err := next.PublicKeys.Tau.verify(previous, "tau")
+	if err != nil {
+		return err
+	}
+	rAlpha, err := next.PublicKeys.Alpha.verify(previous, "alpha")
+	if err != nil {
+		return err
+	}
+	rBeta, err := next.PublicKeys.Beta.verify(previous, "beta")
+	if err != nil {
+		return err
+	}
+	if !sameRatio(next.G1.Tau[1], p.G1.Tau[1], next.PublicKeys.Tau.XR, rTau) {
+		return fmt.Errorf("%w : tau update", ErrInvalidContribution)
+	}
+	if !sameRatio(next.G1.AlphaTau[0], p.G1.AlphaTau[0], next.PublicKeys.Alpha.XR, rAlpha) {
+		return fmt.Errorf("%w : alpha update", ErrInvalidContribution)
+	}
+	if !sameRatio(next.G1.BetaTau[0], p.G1.BetaTau[0], next.PublicKeys.Beta.XR, rBeta) {
+		return fmt.Errorf("%w : beta update", ErrInvalidContribution)
+	}
+	return next.wellFormed()
+}
+
+// wellFormed checks the points are consistent powers of the same secrets
+func (p *Phase1) wellFormed() error {
+	_, _, g1, g2 := curve.Generators()
+	n := p.N()
+	if n < 2 || len(p.G1.Tau) != 2*n || len(p.G1.AlphaTau) != n || len(p.G1.BetaTau) != n {
+		return fmt.Errorf("%w : sizes", ErrInvalidContribution)
+	}
+	if !p.G1.Tau[0].Equal(&g1) || !p.G2.Tau[0].Equal(&g2) {
+		return fmt.Errorf("%w : first power is not the generator", ErrInvalidContribution)
+	}
+	if p.G1.Tau[1].IsInfinity() || p.G1.AlphaTau[0].IsInfinity() || p.G1.BetaTau[0].IsInfinity() {
+		return fmt.Errorf("%w : secret set to zero", ErrInvalidContribution)
+	}
+
+	tauG2 := p.G2.Tau[1]
+	if !sameRatio(p.G1.Tau[1], g1, tauG2, g2) {
+		return fmt.Errorf("%w : tau differs in G1 and G2", ErrInvalidContribution)
+	}
+	if !sameRatio(p.G1.BetaTau[0], g1, p.G2.Beta, g2) {
+		return fmt.Errorf("%w : beta differs in G1 and G2", ErrInvalidContribution)
+	}
+
+	r, err := randomScalars(len(p.G1.Tau))
+	if err != nil {
+		return err
+	}
+	for name, points := range map[string][]curve.G1Affine{
+		"tau":       p.G1.Tau,
+		"alpha tau": p.G1.AlphaTau,
+		"beta tau":  p.G1.BetaTau,
+	} {
+		l, shifted, err := linearCombinationG1(points, r)
+		if err != nil {
+			return err
+		}
+		if !sameRatio(shifted, l, tauG2, g2) {
+			return fmt.Errorf("%w : %s powers", ErrInvalidContribution, name)
+		}
+	}
+	l, shifted, err := linearCombinationG2(p.G2.Tau, r)
+	if err != nil {
+		return err
+	}
+	if !sameRatio(p.G1.Tau[1], g1, shifted, l) {
+		return fmt.Errorf("%w : tau powers in G2", ErrInvalidContribution)
+	}
+	return nil
+}
+
+func (p *Phase1) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := curve.NewEncoder(cw)
+	if err := writeName(enc, cw, p.Name); err != nil {
+		return cw.n, err
+	}
+	toEncode := []interface{}{
+		p.Previous,
+		&p.PublicKeys.Tau.S, &p.PublicKeys.Tau.SX, &p.PublicKeys.Tau.XR,
+		&p.PublicKeys.Alpha.S, &p.PublicKeys.Alpha.SX, &p.PublicKeys.Alpha.XR,
+		&p.PublicKeys.Beta.S, &p.PublicKeys.Beta.SX, &p.PublicKeys.Beta.XR,
+		p.G1.Tau,
+		p.G1.AlphaTau,
+		p.G1.BetaTau,
+		p.G2.Tau,
+		&p.G2.Beta,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+func (p *Phase1) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	dec := curve.NewDecoder(cr)
+	var err error
+	if p.Name, err = readName(dec, cr); err != nil {
+		return cr.n, err
+	}
+	toDecode := []interface{}{
+		&p.Previous,
+		&p.PublicKeys.Tau.S, &p.PublicKeys.Tau.SX, &p.PublicKeys.Tau.XR,
+		&p.PublicKeys.Alpha.S, &p.PublicKeys.Alpha.SX, &p.PublicKeys.Alpha.XR,
+		&p.PublicKeys.Beta.S, &p.PublicKeys.Beta.SX, &p.PublicKeys.Beta.XR,
+		&p.G1.Tau,
+		&p.G1.AlphaTau,
+		&p.G1.BetaTau,
+		&p.G2.Tau,
+		&p.G2.Beta,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return cr.n, err
+		}
+	}
+	return cr.n, nil
+}
+
+func fill(p curve.G1Affine, n int) []curve.G1Affine {
+	points := make([]curve.G1Affine, n)
+	for i := range points {
+		points[i] = p
+	}
+	return points
+}
@@ -0,0 +1,309 @@
+package ceremony
+
+import (
+	"errors"
+	"fmt"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/constraint"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"io"
+	"sync"
+)
+
+// Evaluations are the circuit terms derived from phase 1, no contribution changes them.
+// Gamma is left to the generator, the public terms are not divided by it.
+type Evaluations struct {
+	Phase1  Hash // the phase 1 contribution the terms are derived from
+	Circuit Hash // sha256 of the serialized R1CS
+
+	G1 struct {
+		Alpha, Beta curve.G1Affine
+		A, B        []curve.G1Affine // [A_j(τ)]1, [B_j(τ)]1 for every wire
+		VK          []curve.G1Affine // [βA_j(τ) + αB_j(τ) + C_j(τ)]1 for the public wires
+	}
+	G2 struct {
+		Beta curve.G2Affine
+		B    []curve.G2Affine // [B_j(τ)]2 for every wire
+	}
+}
+
+// Phase2 is a contribution to delta, the only secret specific to a circuit
+type Phase2 struct {
+	Name     string // contributor, informative only
+	Previous Hash   // hash of the previous contribution, or of the evaluations for the initial parameters
+
+	PublicKey PublicKey // proof of knowledge of the contributed delta
+
+	G1 struct {
+		Delta curve.G1Affine
+		Z     []curve.G1Affine // [τ^i(τ^N - 1)/δ]1 in bit reversed order
+		PK    []curve.G1Affine // [(βA_j(τ) + αB_j(τ) + C_j(τ))/δ]1 for the private wires
+	}
+	G2 struct {
+		Delta curve.G2Affine
+	}
+}
+
+// NewPhase2 derives the evaluations of the circuit and the initial phase 2 parameters,
+// with delta set to one, from the last phase 1 contribution
+func NewPhase2(r1cs *cs.R1CS, p1 *Phase1, p1Hash, circuit Hash) (*Evaluations, *Phase2, error) {
+	if r1cs.CommitmentInfo.Is() {
+		return nil, nil, errors.New("circuits with commitments are not supported by the ceremony")
+	}
+	domain := fft.NewDomain(uint64(len(r1cs.Constraints)))
+	n := int(domain.Cardinality)
+	if n > p1.N() {
+		return nil, nil, fmt.Errorf("circuit needs %d powers of tau, phase 1 has %d", n, p1.N())
+	}
+
+	// lagrange basis of the domain in the exponent
+	var tau, alphaTau, betaTau []curve.G1Affine
+	var tauG2 []curve.G2Affine
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() { defer wg.Done(); tau = lagrangeG1(p1.G1.Tau[:n], domain) }()
+	go func() { defer wg.Done(); alphaTau = lagrangeG1(p1.G1.AlphaTau[:n], domain) }()
+	go func() { defer wg.Done(); betaTau = lagrangeG1(p1.G1.BetaTau[:n], domain) }()
+	go func() { defer wg.Done(); tauG2 = lagrangeG2(p1.G2.Tau[:n], domain) }()
+	wg.Wait()
+
+	// each wire accumulates the lagrange terms of the constraints it appears in,
+	// L to A, R to B and all of them to K = βA + αB + C
+	nbWires := r1cs.NbInternalVariables + r1cs.GetNbPublicVariables() + r1cs.GetNbSecretVariables()
+	a := make([]curve.G1Jac, nbWires)
+	b := make([]curve.G1Jac, nbWires)
+	bG2 := make([]curve.G2Jac, nbWires)
+	k := make([]curve.G1Jac, nbWires)
+	forTerms := func(linearExpression func(c *constraint.R1C) constraint.LinearExpression, add func(i int, t constraint.Term)) {
+		for i := range r1cs.Constraints {
+			for _, t := range linearExpression(&r1cs.Constraints[i]) {
+				add(i, t)
+			}
+		}
+	}
+	l := func(c *constraint.R1C) constraint.LinearExpression { return c.L }
+	r := func(c *constraint.R1C) constraint.LinearExpression { return c.R }
+	o := func(c *constraint.R1C) constraint.LinearExpression { return c.O }
+	coeff := func(t constraint.Term) *fr.Element { return &r1cs.Coefficients[t.CoeffID()] }
+
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		forTerms(l, func(i int, t constraint.Term) { accumulateG1(&a[t.WireID()], &tau[i], coeff(t)) })
+	}()
+	go func() {
+		defer wg.Done()
+		forTerms(r, func(i int, t constraint.Term) { accumulateG1(&b[t.WireID()], &tau[i], coeff(t)) })
+	}()
+	go func() {
+		defer wg.Done()
+		forTerms(r, func(i int, t constraint.Term) { accumulateG2(&bG2[t.WireID()], &tauG2[i], coeff(t)) })
+	}()
+	go func() {
+		defer wg.Done()
+		forTerms(l, func(i int, t constraint.Term) { accumulateG1(&k[t.WireID()], &betaTau[i], coeff(t)) })
+		forTerms(r, func(i int, t constraint.Term) { accumulateG1(&k[t.WireID()], &alphaTau[i], coeff(t)) })
+		forTerms(o, func(i int, t constraint.Term) { accumulateG1(&k[t.WireID()], &tau[i], coeff(t)) })
+	}()
+	wg.Wait()
+
+	eval := &Evaluations{Phase1: p1Hash, Circuit: circuit}
+	eval.G1.Alpha = p1.G1.AlphaTau[0]
+	eval.G1.Beta = p1.G1.BetaTau[0]
+	eval.G2.Beta = p1.G2.Beta
+	eval.G1.A = curve.BatchJacobianToAffineG1(a)
+	eval.G1.B = curve.BatchJacobianToAffineG1(b)
+	eval.G2.B = make([]curve.G2Affine, nbWires)
+	for i := range bG2 {
+		eval.G2.B[i].FromJacobian(&bG2[i])
+	}
+	kAff := curve.BatchJacobianToAffineG1(k)
+	nbPublic := r1cs.GetNbPublicVariables()
+	eval.G1.VK = kAff[:nbPublic]
+
+	// the vanishing polynomial times the powers, τ^i(τ^N - 1) = τ^(i+N) - τ^i
+	_, _, g1, g2 := curve.Generators()
+	p2 := &Phase2{}
+	p2.G1.Delta = g1
+	p2.G2.Delta = g2
+	p2.G1.PK = append([]curve.G1Affine{}, kAff[nbPublic:]...)
+	p2.G1.Z = make([]curve.G1Affine, n)
+	parallelize(n, func(start, end int) {
+		for i := start; i < end; i++ {
+			p2.G1.Z[i].Sub(&p1.G1.Tau[i+n], &p1.G1.Tau[i])
+		}
+	})
+	bitReverseG1(p2.G1.Z)
+
+	var err error
+	if p2.Previous, err = hashOf(eval); err != nil {
+		return nil, nil, err
+	}
+	return eval, p2, nil
+}
+
+// Contribute returns the parameters updated with a fresh delta, dropped once applied
+func (p *Phase2) Contribute(previous Hash, name string) (*Phase2, error) {
+	delta, err := nonZeroRandom()
+	if err != nil {
+		return nil, err
+	}
+	var deltaInv fr.Element
+	deltaInv.Inverse(&delta)
+	defer func() {
+		delta.SetZero()
+		deltaInv.SetZero()
+	}()
+
+	next := &Phase2{Name: name, Previous: previous}
+	if next.PublicKey, err = newPublicKey(delta, previous, "delta"); err != nil {
+		return nil, err
+	}
+	next.G1.Delta.ScalarMultiplication(&p.G1.Delta, toBig(&delta))
+	next.G2.Delta.ScalarMultiplication(&p.G2.Delta, toBig(&delta))
+
+	next.G1.Z = append([]curve.G1Affine{}, p.G1.Z...)
+	next.G1.PK = append([]curve.G1Affine{}, p.G1.PK...)
+	scaleG1(next.G1.Z, repeat(deltaInv, len(next.G1.Z)))
+	scaleG1(next.G1.PK, repeat(deltaInv, len(next.G1.PK)))
+	return next, nil
+}
+
+// Verify checks next is a contribution on top of p, whose hash is previous
+func (p *Phase2) Verify(next *Phase2, previous Hash) error {
+	if next.Previous != previous {
+		return fmt.Errorf("%w : previous hash %s, expected %s", ErrInvalidContribution, next.Previous, previous)
+	}
+	if len(next.G1.Z) != len(p.G1.Z) || len(next.G1.PK) != len(p.G1.PK) {
+		return fmt.Errorf("%w : size changed", ErrInvalidContribution)
+	}
+
+	r, err := next.PublicKey.verify(previous, "delta")
+	if err != nil {
+		return err
+	}
+	if !sameRatio(next.G1.Delta, p.G1.Delta, next.PublicKey.XR, r) {
+		return fmt.Errorf("%w : delta update", ErrInvalidContribution)
+	}
+	_, _, g1, g2 := curve.Generators()
+	if !sameRatio(next.G1.Delta, g1, next.G2.Delta, g2) {
+		return fmt.Errorf("%w : delta differs in G1 and G2", ErrInvalidContribution)
+	}
+
+	// every point divided by the contributed delta, checked on a random combination
+	prevPoints := append(append([]curve.G1Affine{}, p.G1.Z...), p.G1.PK...)
+	nextPoints := append(append([]curve.G1Affine{}, next.G1.Z...), next.G1.PK...)
+	scalars, err := randomScalars(len(prevPoints))
+	if err != nil {
+		return err
+	}
+	prevL, err := combineG1(prevPoints, scalars)
+	if err != nil {
+		return err
+	}
+	nextL, err := combineG1(nextPoints, scalars)
+	if err != nil {
+		return err
+	}
+	if !sameRatio(nextL, prevL, p.G2.Delta, next.G2.Delta) {
+		return fmt.Errorf("%w : proving key not divided by delta", ErrInvalidContribution)
+	}
+	return nil
+}
+
+func (e *Evaluations) WriteTo(w io.Writer) (int64, error) {
+	enc := curve.NewEncoder(w)
+	toEncode := []interface{}{
+		e.Phase1,
+		e.Circuit,
+		&e.G1.Alpha,
+		&e.G1.Beta,
+		e.G1.A,
+		e.G1.B,
+		e.G1.VK,
+		&e.G2.Beta,
+		e.G2.B,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return enc.BytesWritten(), err
+		}
+	}
+	return enc.BytesWritten(), nil
+}
+
+func (e *Evaluations) ReadFrom(r io.Reader) (int64, error) {
+	dec := curve.NewDecoder(r)
+	toDecode := []interface{}{
+		&e.Phase1,
+		&e.Circuit,
+		&e.G1.Alpha,
+		&e.G1.Beta,
+		&e.G1.A,
+		&e.G1.B,
+		&e.G1.VK,
+		&e.G2.Beta,
+		&e.G2.B,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return dec.BytesRead(), err
+		}
+	}
+	return dec.BytesRead(), nil
+}
+
+func (p *Phase2) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := curve.NewEncoder(cw)
+	if err := writeName(enc, cw, p.Name); err != nil {
+		return cw.n, err
+	}
+	toEncode := []interface{}{
+		p.Previous,
+		&p.PublicKey.S, &p.PublicKey.SX, &p.PublicKey.XR,
+		&p.G1.Delta,
+		p.G1.Z,
+		p.G1.PK,
+		&p.G2.Delta,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+func (p *Phase2) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	dec := curve.NewDecoder(cr)
+	var err error
+	if p.Name, err = readName(dec, cr); err != nil {
+		return cr.n, err
+	}
+	toDecode := []interface{}{
+		&p.Previous,
+		&p.PublicKey.S, &p.PublicKey.SX, &p.PublicKey.XR,
+		&p.G1.Delta,
+		&p.G1.Z,
+		&p.G1.PK,
+		&p.G2.Delta,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return cr.n, err
+		}
+	}
+	return cr.n, nil
+}
+
+func repeat(x fr.Element, n int) []fr.Element {
+	s := make([]fr.Element, n)
+	for i := range s {
+		s[i] = x
+	}
+	return s
+}
@@ -0,0 +1,310 @@
+package ceremony
+
+import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	cs "github.com/consensys/gnark/constraint/bn254"
+	"math/bits"
+	"os"
+	"path/filepath"
+	models2 "smart-contract-service/models/circuit"
+	"sort"
+)
+
+const (
+	phase1Dir       = "phase1"
+	phase1Ext       = ".ph1"
+	phase2Ext       = ".ph2"
+	evaluationsFile = "evaluations" + phase2Ext
+)
+
+// Record identifies a contribution of the transcript
+type Record struct {
+	Index int
+	Name  string
+	Hash  Hash
+}
+
+// Transcript is the directory a ceremony is stored in: phase1/NNNN.ph1 for the powers
+// of tau, then <circuit>/evaluations.ph2 and <circuit>/NNNN.ph2 for each circuit.
+// Contributors run one step at a time on a copy of the directory and hand it over.
+type Transcript struct {
+	Dir string
+}
+
+func NewTranscript(dir string) *Transcript {
+	return &Transcript{Dir: dir}
+}
+
+// InitPhase1 writes the initial powers of tau for circuits of up to 2^power constraints
+func (t *Transcript) InitPhase1(power int) (Record, error) {
+	if power < 1 || power > 28 {
+		return Record{}, fmt.Errorf("power %d out of range", power)
+	}
+	if files, _ := t.files(t.phase1Dir(), phase1Ext); len(files) > 0 {
+		return Record{}, errors.New("phase 1 already initialized in " + t.phase1Dir())
+	}
+	if err := os.MkdirAll(t.phase1Dir(), 0755); err != nil {
+		return Record{}, err
+	}
+	hash, err := writeFile(NewPhase1(power), t.file(t.phase1Dir(), 0, phase1Ext))
+	return Record{Hash: hash}, err
+}
+
+// ContributePhase1 adds a contribution on top of the last phase 1 one
+func (t *Transcript) ContributePhase1(name string) (Record, error) {
+	files, err := t.files(t.phase1Dir(), phase1Ext)
+	if err != nil {
+		return Record{}, err
+	}
+	last := &Phase1{}
+	hash, err := readFile(last, files[len(files)-1])
+	if err != nil {
+		return Record{}, err
+	}
+	next, err := last.Contribute(hash, name)
+	if err != nil {
+		return Record{}, err
+	}
+	index := len(files)
+	hash, err = writeFile(next, t.file(t.phase1Dir(), index, phase1Ext))
+	return Record{Index: index, Name: name, Hash: hash}, err
+}
+
+// VerifyPhase1 replays the powers of tau from the initial parameters and returns the
+// contributions with the last one
+func (t *Transcript) VerifyPhase1() ([]Record, *Phase1, error) {
+	files, err := t.files(t.phase1Dir(), phase1Ext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prev := &Phase1{}
+	hash, err := readFile(prev, files[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	initial, err := hashOf(NewPhase1(bits.TrailingZeros(uint(prev.N()))))
+	if err != nil {
+		return nil, nil, err
+	}
+	if hash != initial {
+		return nil, nil, fmt.Errorf("%w : %s is not the initial phase 1", ErrTranscriptMismatch, files[0])
+	}
+
+	records := []Record{{Hash: hash}}
+	for i := 1; i < len(files); i++ {
+		next := &Phase1{}
+		nextHash, err := readFile(next, files[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = prev.Verify(next, hash); err != nil {
+			return nil, nil, fmt.Errorf("%s : %w", files[i], err)
+		}
+		records = append(records, Record{Index: i, Name: next.Name, Hash: nextHash})
+		prev, hash = next, nextHash
+	}
+	return records, prev, nil
+}
+
+// InitPhase2 derives the phase 2 of a groth16 circuit from the verified phase 1
+func (t *Transcript) InitPhase2(def *models2.Definition) (Record, error) {
+	dir := t.circuitDir(def)
+	if files, _ := t.files(dir, phase2Ext); len(files) > 0 {
+		return Record{}, errors.New("phase 2 already initialized in " + dir)
+	}
+	records, p1, err := t.VerifyPhase1()
+	if err != nil {
+		return Record{}, err
+	}
+	if len(records) < 2 {
+		return Record{}, errors.New("no phase 1 contribution, tau would be known")
+	}
+	eval, p2, err := t.newPhase2(def, p1, records[len(records)-1].Hash)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return Record{}, err
+	}
+	if _, err = writeFile(eval, filepath.Join(dir, evaluationsFile)); err != nil {
+		return Record{}, err
+	}
+	hash, err := writeFile(p2, t.file(dir, 0, phase2Ext))
+	return Record{Hash: hash}, err
+}
+
+// Contribute adds a contribution on top of the last phase 2 one of the circuit
+func (t *Transcript) Contribute(def *models2.Definition, name string) (Record, error) {
+	dir := t.circuitDir(def)
+	files, err := t.files(dir, phase2Ext)
+	if err != nil {
+		return Record{}, err
+	}
+	last := &Phase2{}
+	hash, err := readFile(last, files[len(files)-1])
+	if err != nil {
+		return Record{}, err
+	}
+	next, err := last.Contribute(hash, name)
+	if err != nil {
+		return Record{}, err
+	}
+	index := len(files)
+	hash, err = writeFile(next, t.file(dir, index, phase2Ext))
+	return Record{Index: index, Name: name, Hash: hash}, err
+}
+
+// Verify replays the whole transcript of the circuit: the powers of tau, the
+// derivation of the circuit terms and every delta contribution
+func (t *Transcript) Verify(def *models2.Definition) ([]Record, *Evaluations, *Phase2, error) {
+	dir := t.circuitDir(def)
+	files, err := t.files(dir, phase2Ext)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	eval := &Evaluations{}
+	if _, err = readFile(eval, filepath.Join(dir, evaluationsFile)); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// phase 2 was derived from a phase 1 contribution, later ones are ignored
+	p1Records, _, err := t.VerifyPhase1()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var p1 *Phase1
+	for _, record := range p1Records {
+		if record.Hash == eval.Phase1 {
+			p1 = &Phase1{}
+			if _, err = readFile(p1, t.file(t.phase1Dir(), record.Index, phase1Ext)); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	if p1 == nil {
+		return nil, nil, nil, fmt.Errorf("%w : phase 1 contribution %s not found", ErrTranscriptMismatch, eval.Phase1)
+	}
+
+	expectedEval, prev, err := t.newPhase2(def, p1, eval.Phase1)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	evalHash, err := hashOf(eval)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if expected, err := hashOf(expectedEval); err != nil || expected != evalHash {
+		return nil, nil, nil, fmt.Errorf("%w : evaluations do not derive from phase 1 and the circuit", ErrTranscriptMismatch)
+	}
+	initial := &Phase2{}
+	hash, err := readFile(initial, files[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if expected, err := hashOf(prev); err != nil || expected != hash {
+		return nil, nil, nil, fmt.Errorf("%w : %s is not the initial phase 2", ErrTranscriptMismatch, files[0])
+	}
+
+	records := []Record{{Hash: hash}}
+	for i := 1; i < len(files); i++ {
+		next := &Phase2{}
+		nextHash, err := readFile(next, files[i])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err = prev.Verify(next, hash); err != nil {
+			return nil, nil, nil, fmt.Errorf("%s : %w", files[i], err)
+		}
+		records = append(records, Record{Index: i, Name: next.Name, Hash: nextHash})
+		prev, hash = next, nextHash
+	}
+	return records, eval, prev, nil
+}
+
+// Finalize verifies the transcript of the circuit and returns its groth16 keys with
+// the last contribution they come from
+func (t *Transcript) Finalize(def *models2.Definition) (*models2.Keys, Record, error) {
+	records, eval, p2, err := t.Verify(def)
+	if err != nil {
+		return nil, Record{}, err
+	}
+	last := records[len(records)-1]
+	if len(records) < 2 {
+		return nil, last, errors.New("no phase 2 contribution, the keys would have a known delta")
+	}
+	r1cs, err := compile(def)
+	if err != nil {
+		return nil, last, err
+	}
+	pk, vk, err := NewKeys(r1cs, eval, p2)
+	if err != nil {
+		return nil, last, err
+	}
+	return &models2.Keys{Curve: ecc.BN254, Backend: backend.GROTH16, Cs: r1cs, Pk: pk, Vk: vk}, last, nil
+}
+
+func (t *Transcript) newPhase2(def *models2.Definition, p1 *Phase1, p1Hash Hash) (*Evaluations, *Phase2, error) {
+	r1cs, err := compile(def)
+	if err != nil {
+		return nil, nil, err
+	}
+	circuit, err := hashOf(r1cs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewPhase2(r1cs, p1, p1Hash, circuit)
+}
+
+func compile(def *models2.Definition) (*cs.R1CS, error) {
+	if !def.Accepts(backend.GROTH16) {
+		return nil, fmt.Errorf("%w : %s is not a groth16 circuit", models2.ErrBackendNotSupported, def.Name)
+	}
+	if def.Curve != ecc.BN254 {
+		return nil, fmt.Errorf("%w : the ceremony runs on BN254, %s is on %s", models2.ErrCurveNotSupported, def.Name, def.Curve)
+	}
+	ccs, err := models2.Compile(def.Curve, backend.GROTH16, def.Circuit())
+	if err != nil {
+		return nil, err
+	}
+	r1cs, ok := ccs.(*cs.R1CS)
+	if !ok {
+		return nil, errors.New("circuit is not a BN254 R1CS")
+	}
+	return r1cs, nil
+}
+
+func (t *Transcript) phase1Dir() string {
+	return filepath.Join(t.Dir, phase1Dir)
+}
+
+func (t *Transcript) circuitDir(def *models2.Definition) string {
+	return filepath.Join(t.Dir, def.Name)
+}
+
+func (t *Transcript) file(dir string, index int, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%04d%s", index, ext))
+}
+
+// files lists the contributions of a directory in order, it fails when there are none
+// or when one is missing
+func (t *Transcript) files(dir, ext string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9][0-9]"+ext))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no contribution in " + dir + ", initialize it first")
+	}
+	sort.Strings(files)
+	for i, file := range files {
+		if file != t.file(dir, i, ext) {
+			return nil, fmt.Errorf("%w : contribution %d missing in %s", ErrTranscriptMismatch, i, dir)
+		}
+	}
+	return files, nil
+}
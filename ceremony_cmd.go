@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	log "github.com/sirupsen/logrus"
+	"math/bits"
+	"os"
+	"smart-contract-service/ceremony"
+	"smart-contract-service/configuration"
+	"smart-contract-service/internal"
+	models2 "smart-contract-service/models/circuit"
+)
+
+const ceremonyUsage = `usage: ceremony <command> [flags]
+
+phase 1, shared by every groth16 circuit:
+  phase1-init        write the initial powers of tau
+  phase1-contribute  add a contribution with fresh randomness
+  phase1-verify      replay the powers of tau transcript
+
+phase 2, per circuit:
+  init               derive the circuit terms from the compiled R1CS and phase 1
+  contribute         add a delta contribution with fresh randomness
+  verify             replay the whole transcript of the circuit
+  finalize           verify, then write the groth16 keys and their manifest entry
+`
+
+// runCeremony runs one step of the groth16 trusted setup ceremony. Contributors run
+// their step on the transcript directory and hand it to the next one, the keys are
+// sound as long as one of them did not keep their randomness.
+func runCeremony(args []string) {
+	if len(args) == 0 {
+		fmt.Print(ceremonyUsage)
+		os.Exit(2)
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("ceremony "+command, flag.ExitOnError)
+	dir := flags.String("dir", internal.CeremonyDir, "transcript directory")
+	name := flags.String("name", "", "contributor name recorded in the transcript")
+	circuit := flags.String("circuit", "", "algorithm name of the groth16 circuit")
+	power := flags.Int("power", 0, "phase 1 supports circuits of up to 2^power constraints, defaults to the largest groth16 circuit")
+	flags.Parse(args[1:])
+
+	config := configuration.ServiceApp{EnvVariable: "DEV", Path: setPath()}
+	config.Load()
+	configureCircuits(config.Config)
+
+	transcript := ceremony.NewTranscript(*dir)
+	switch command {
+	case "phase1-init":
+		if *power == 0 {
+			*power = ceremonyPower()
+		}
+		record, err := transcript.InitPhase1(*power)
+		assertNoError(err)
+		logRecord("phase 1 initialized", record)
+	case "phase1-contribute":
+		record, err := transcript.ContributePhase1(*name)
+		assertNoError(err)
+		logRecord("phase 1 contribution", record)
+	case "phase1-verify":
+		records, _, err := transcript.VerifyPhase1()
+		assertNoError(err)
+		for _, record := range records {
+			logRecord("phase 1 verified", record)
+		}
+	case "init":
+		record, err := transcript.InitPhase2(ceremonyCircuit(*circuit))
+		assertNoError(err)
+		logRecord("phase 2 initialized", record)
+	case "contribute":
+		record, err := transcript.Contribute(ceremonyCircuit(*circuit), *name)
+		assertNoError(err)
+		logRecord("phase 2 contribution", record)
+	case "verify":
+		records, _, _, err := transcript.Verify(ceremonyCircuit(*circuit))
+		assertNoError(err)
+		for _, record := range records {
+			logRecord("phase 2 verified", record)
+		}
+	case "finalize":
+		finalizeCeremony(config.Config, transcript, ceremonyCircuit(*circuit))
+	default:
+		fmt.Print(ceremonyUsage)
+		os.Exit(2)
+	}
+}
+
+// finalizeCeremony replaces the groth16 artifacts of the circuit with the ceremony keys
+// and re-signs the manifest, the other circuits keep their entries
+func finalizeCeremony(config configuration.ConfigApp, transcript *ceremony.Transcript, def *models2.Definition) {
+	// the entries of the other circuits are signed again, they must be the published ones
+	manifest, err := models2.ReadManifest(internal.ManifestPath)
+	if err != nil {
+		log.Fatalf("%s cannot be read, run -init before finalizing a ceremony : %s", internal.ManifestPath, err)
+	}
+	publicKey, err := internal.GeneratePublicKey(config)
+	assertNoError(err)
+	if err = manifest.Verify(publicKey); err != nil {
+		log.Fatalf("%s is not signed by the service key : %s", internal.ManifestPath, err)
+	}
+
+	keys, last, err := transcript.Finalize(def)
+	assertNoError(err)
+	logRecord("phase 2 finalized", last)
+
+	artifact := def.Artifact.For(backend.GROTH16)
+	log.Println("serialize constraint system (circuit)", artifact.R1cs)
+	assertNoError(internal.Serialize(keys.Cs, artifact.R1cs))
+	log.Println("serialize proving key", artifact.Pk)
+	assertNoError(internal.Serialize(keys.Pk, artifact.Pk))
+	log.Println("serialize verifying key", artifact.Vk)
+	assertNoError(internal.Serialize(keys.Vk, artifact.Vk))
+	log.Println("export solidity verifier", artifact.Solidity)
+	assertNoError(internal.ExportSolidity(keys.Vk, artifact.Solidity))
+
+	entry, err := models2.NewManifestEntry(def, backend.GROTH16, keys.Cs.GetNbConstraints())
+	assertNoError(err)
+	entry.Ceremony = last.Hash.String()
+
+	replaced := false
+	for i := range manifest.Circuits {
+		b, err := models2.ParseBackend(manifest.Circuits[i].Backend)
+		if err == nil && manifest.Circuits[i].Circuit == def.Name && b == backend.GROTH16 {
+			manifest.Circuits[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		manifest.Circuits = append(manifest.Circuits, entry)
+	}
+	writeManifest(config, manifest)
+}
+
+// ceremonyCircuit returns the registered circuit a phase 2 runs for
+func ceremonyCircuit(name string) *models2.Definition {
+	if name == "" {
+		log.Fatal("-circuit is required")
+	}
+	def, err := models2.Lookup(name)
+	assertNoError(err)
+	return def
+}
+
+// ceremonyPower returns the phase 1 size fitting every BN254 groth16 circuit
+func ceremonyPower() int {
+	power := 1
+	for _, def := range models2.Definitions() {
+		if !def.Accepts(backend.GROTH16) || def.Curve != ecc.BN254 {
+			continue
+		}
+		ccs, err := models2.Compile(def.Curve, backend.GROTH16, def.Circuit())
+		assertNoError(err)
+		n := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()))
+		if p := bits.TrailingZeros64(n); p > power {
+			power = p
+		}
+	}
+	return power
+}
+
+func logRecord(msg string, record ceremony.Record) {
+	log.WithFields(log.Fields{
+		"index":  record.Index,
+		"name":   record.Name,
+		"sha256": record.Hash.String(),
+	}).Info(msg)
+}
@@ -0,0 +1,114 @@
+// Package client proves registered circuits on the partner side, the resulting
+// request is submitted to the /proof/external endpoint so the secret witness
+// never leaves the partner.
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	models2 "smart-contract-service/models/circuit"
+)
+
+type Prover struct {
+	def  *models2.Definition
+	keys *models2.Keys
+}
+
+// NewProver loads the published R1CS and groth16 proving key of a registered circuit,
+// both can be downloaded from GET /circuit/:algo/r1cs and GET /circuit/:algo/pk
+func NewProver(algo, r1csFile, pkFile string) (*Prover, error) {
+	return newProver(algo, backend.GROTH16, r1csFile, pkFile, "")
+}
+
+// NewPlonkProver loads the published constraint system, plonk proving key and kzg srs of
+// a registered circuit, from GET /circuit/:algo/{r1cs,pk,srs}?backend=plonk
+func NewPlonkProver(algo, scsFile, pkFile, srsFile string) (*Prover, error) {
+	return newProver(algo, backend.PLONK, scsFile, pkFile, srsFile)
+}
+
+func newProver(algo string, b backend.ID, csFile, pkFile, srsFile string) (*Prover, error) {
+	def, err := models2.Lookup(algo)
+	if err != nil {
+		return nil, err
+	}
+	if !def.Accepts(b) {
+		return nil, fmt.Errorf("%w : %s for %s", models2.ErrBackendNotSupported, b, def.Name)
+	}
+
+	// the artifacts are those of the curve the circuit is registered on
+	keys, err := models2.NewKeys(def.Curve, b)
+	if err != nil {
+		return nil, err
+	}
+	if err = internal.Deserialize(keys.Cs, csFile); err != nil {
+		return nil, err
+	}
+	if err = internal.Deserialize(keys.Pk, pkFile); err != nil {
+		return nil, err
+	}
+	if b == backend.PLONK {
+		srs, err := models2.NewEmptySRS(def.Curve)
+		if err != nil {
+			return nil, err
+		}
+		if err = internal.Deserialize(srs, srsFile); err != nil {
+			return nil, err
+		}
+		if err = keys.InitKZG(srs); err != nil {
+			return nil, err
+		}
+	}
+	return &Prover{def: def, keys: keys}, nil
+}
+
+// Prove proves a full assignment of the circuit for the customer
+func (p *Prover) Prove(customerId string, assignment frontend.Circuit) (*models.ExternalProofRequest, error) {
+	witness, err := frontend.NewWitness(assignment, p.keys.Curve.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := p.keys.Prove(witness)
+	if err != nil {
+		return nil, err
+	}
+
+	var proofBuf bytes.Buffer
+	if _, err = proof.WriteTo(&proofBuf); err != nil {
+		return nil, err
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, err
+	}
+	dataBin, err := publicWitness.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExternalProofRequest{
+		Algo:          p.def.Name,
+		Backend:       p.keys.Backend.String(),
+		Curve:         p.keys.Curve.String(),
+		CustomerId:    customerId,
+		Proof:         base64.StdEncoding.EncodeToString(proofBuf.Bytes()),
+		PublicWitness: base64.StdEncoding.EncodeToString(dataBin),
+	}, nil
+}
+
+// ProveCustomer builds the assignment from the customer data and the EdDSA key held by the partner
+func (p *Prover) ProveCustomer(in *models2.WitnessInput) (*models.ExternalProofRequest, error) {
+	input := *in
+	input.Curve = p.def.Curve
+	assignment, err := p.def.Assign(&input)
+	if err != nil {
+		return nil, err
+	}
+	return p.Prove(in.Customer.Id, assignment)
+}
@@ -1,19 +1,43 @@
 package configuration
 
 type ConfigApp struct {
-	ListenPort         string `split_words:"true" default:":9800"`
-	AppName            string `split_words:"true" default:"Smart Contract Service"`
-	Version            string `split_words:"true" default:"0.0.1"`
-	RootURL            string `split_words:"true" default:"/service/smart-contract"`
-	Timeout            int    `split_words:"true" default:"4000"`
-	Env                string `split_words:"true" default:"dev"`
-	PostgreConnection  string `split_words:"true" default:"host=127.0.0.1 port=5432 dbname=postgres user=postgres password=Sandiaman123. sslmode=disable"`
-	SSLMode            string `split_words:"true" default:"disable"`
-	LogMode            bool   `split_words:"true" default:"false"`
-	RedisConnection    string `split_words:"true" default:"localhost:6379"`
-	Secret             string `split_words:"true" default:"rahasia"`
-	Expire             int    `split_words:"true" default:"5"`
-	RefreshTokenExpire int    `split_words:"true" default:"7"`
-	PublicKeyLocation  string `split_words:"true" default:"./assets/rsa256-public.pem"`
-	PrivateKeyLocation string `split_words:"true" default:"./assets/rsa256-private.pem"`
+	ListenPort             string   `split_words:"true" default:":9800"`
+	AppName                string   `split_words:"true" default:"Smart Contract Service"`
+	Version                string   `split_words:"true" default:"0.0.1"`
+	RootURL                string   `split_words:"true" default:"/service/smart-contract"`
+	Timeout                int      `split_words:"true" default:"4000"`
+	Env                    string   `split_words:"true" default:"dev"`
+	PostgreConnection      string   `split_words:"true" default:"host=127.0.0.1 port=5432 dbname=postgres user=postgres password=Sandiaman123. sslmode=disable"`
+	SSLMode                string   `split_words:"true" default:"disable"`
+	LogMode                bool     `split_words:"true" default:"false"`
+	RedisConnection        string   `split_words:"true" default:"localhost:6379"`
+	Secret                 string   `split_words:"true" default:"rahasia"`
+	Expire                 int      `split_words:"true" default:"5"`
+	RefreshTokenExpire     int      `split_words:"true" default:"7"`
+	PublicKeyLocation      string   `split_words:"true" default:"./assets/rsa256-public.pem"`
+	PrivateKeyLocation     string   `split_words:"true" default:"./assets/rsa256-private.pem"`
+	KeyReloadInterval      int      `split_words:"true" default:"0"`
+	ProofTokenMode         string   `split_words:"true" default:"redis"`
+	ProofTokenLegacy       bool     `split_words:"true" default:"true"`
+	IssuerKeyLocation      string   `split_words:"true" default:"./assets/eddsa-issuer.pem"`
+	CredentialMinAge       int      `split_words:"true" default:"17"`
+	CredentialBranches     []string `split_words:"true"`
+	BatchProofMaxSize      int      `split_words:"true" default:"5000"`
+	BatchVerifyMaxSize     int      `split_words:"true" default:"1000"`
+	ProofJobWorkers        int      `split_words:"true" default:"2"`
+	ProofJobQueueSize      int      `split_words:"true" default:"10000"`
+	MembershipSyncInterval int      `split_words:"true" default:"60"`
+	MembershipRootHistory  int      `split_words:"true" default:"32"`
+	ProofTokenTTL          int      `split_words:"true" default:"300"`
+
+	// ProofTokenCircuitTTL overrides ProofTokenTTL by circuit, in seconds as hash:600,range:60
+	ProofTokenCircuitTTL map[string]int `split_words:"true"`
+	// CircuitCurves moves circuits off their registered curve, as payment:bls12_377
+	CircuitCurves map[string]string `split_words:"true"`
+	// MembershipReconcileInterval rebuilds the membership tree from the whole customers
+	// table, in seconds, catching changes committed behind the sync watermark
+	MembershipReconcileInterval int `split_words:"true" default:"3600"`
+	// KeyEncryptionKey seals the customer signing keys the service holds, it has no default
+	// and must differ from Secret, the service refuses to start without it
+	KeyEncryptionKey string `split_words:"true"`
 }
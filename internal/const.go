@@ -1,13 +1,10 @@
 package internal
 
 const (
-	R1csPath         = "models/circuit/mimc.r1cs"
-	PkPath           = "models/circuit/mimc.pk"
-	VkPath           = "models/circuit/mimc.vk"
-	R1csEllipticPath = "models/circuit/elliptic.r1cs"
-	PkEllipticPath   = "models/circuit/elliptic.pk"
-	VkEllipticPath   = "models/circuit/elliptic.vk"
-	R1csEddsaPath    = "models/circuit/eddsa.r1cs"
-	PkEddsaPath      = "models/circuit/eddsa.pk"
-	VkEddsaPath      = "models/circuit/eddsa.vk"
+	CircuitDir   = "models/circuit"
+	ManifestPath = "models/circuit/manifest.json"
+	// SrsPath is the universal kzg srs shared by every plonk circuit
+	SrsPath = "models/circuit/kzg.srs"
+	// CeremonyDir holds the groth16 trusted setup transcripts
+	CeremonyDir = "models/circuit/ceremony"
 )
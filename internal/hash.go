@@ -1,12 +1,19 @@
 package internal
 
 import (
+	"crypto"
 	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
 	"golang.org/x/crypto/bcrypt"
+	"io"
 	"math/big"
+	"os"
 )
 
 func MimcHash(data []byte) string {
@@ -21,6 +28,16 @@ func MimcHash(data []byte) string {
 	return hashInt.String()
 }
 
+// FieldBytes maps arbitrary data to a BN254 scalar field element, returned as the
+// 32 bytes big-endian block expected by MiMC
+func FieldBytes(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	var e fr.Element
+	e.SetBytes(digest[:])
+	b := e.Bytes()
+	return b[:]
+}
+
 func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
@@ -36,3 +53,38 @@ func SignHMAC256(msg, key []byte) string {
 	mac.Write(msg)
 	return hex.EncodeToString(mac.Sum(nil))
 }
+
+// FileSha256 returns the hex encoded SHA-256 of a file
+func FileSha256(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SignRSA256 signs the SHA-256 digest of msg and returns the base64 signature
+func SignRSA256(msg []byte, key *rsa.PrivateKey) (string, error) {
+	digest := sha256.Sum256(msg)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifyRSA256 checks a base64 signature produced by SignRSA256
+func VerifyRSA256(msg []byte, signature string, key *rsa.PublicKey) error {
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], decoded)
+}
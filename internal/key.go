@@ -37,3 +37,26 @@ func GeneratePrivateKey(cfg configuration.ConfigApp) (*rsa.PrivateKey, error) {
 	}
 	return privateKey, nil
 }
+
+func GeneratePublicKey(cfg configuration.ConfigApp) (*rsa.PublicKey, error) {
+	pub, err := os.ReadFile(cfg.PublicKeyLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	pubPem, _ := pem.Decode(pub)
+	if pubPem == nil || pubPem.Type != "PUBLIC KEY" {
+		return nil, errors.New("Not Public Key")
+	}
+
+	parsedKey, err := x509.ParsePKIXPublicKey(pubPem.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := parsedKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Not RSA public key")
+	}
+	return publicKey, nil
+}
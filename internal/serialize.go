@@ -1,32 +1,76 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
 	"io"
+	"math/big"
 	"os"
 )
 
+var (
+	ErrShortRead     = errors.New("unexpected end of file")
+	ErrCurveMismatch = errors.New("content does not match the expected curve")
+)
+
 // Serialize gnark object to given file
-func Serialize(gnarkObject io.WriterTo, fileName string) {
+func Serialize(gnarkObject io.WriterTo, fileName string) error {
 	f, err := os.Create(fileName)
 	if err != nil {
-		return
+		return err
 	}
+	defer f.Close()
 
 	_, err = gnarkObject.WriteTo(f)
 	if err != nil {
-		return
+		return fmt.Errorf("write %s: %w", fileName, err)
 	}
+	return f.Close()
 }
 
-// Deserialize gnark object from given file
-func Deserialize(gnarkObject io.ReaderFrom, fileName string) {
+// Deserialize gnark object from given file, the whole file must be consumed
+func Deserialize(gnarkObject io.ReaderFrom, fileName string) error {
 	f, err := os.Open(fileName)
 	if err != nil {
-		return
+		return err
 	}
+	defer f.Close()
 
-	_, err = gnarkObject.ReadFrom(f)
+	info, err := f.Stat()
 	if err != nil {
-		return
+		return err
+	}
+
+	n, err := gnarkObject.ReadFrom(f)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("read %s: %w", fileName, ErrShortRead)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", fileName, err)
+	}
+	if n != info.Size() {
+		// a key of another curve decodes with a different point size
+		return fmt.Errorf("read %s: %d of %d bytes decoded: %w", fileName, n, info.Size(), ErrCurveMismatch)
+	}
+
+	// a constraint system records the scalar field it was compiled over
+	if cs, ok := gnarkObject.(fieldObject); ok && cs.Field().Cmp(cs.CurveID().ScalarField()) != 0 {
+		return fmt.Errorf("read %s: compiled for curve %s: %w", fileName, curveOf(cs.Field()), ErrCurveMismatch)
+	}
+	return nil
+}
+
+type fieldObject interface {
+	CurveID() ecc.ID
+	Field() *big.Int
+}
+
+func curveOf(field *big.Int) ecc.ID {
+	for _, id := range ecc.Implemented() {
+		if id.ScalarField().Cmp(field) == 0 {
+			return id
+		}
 	}
+	return ecc.UNKNOWN
 }
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"io"
+	"math/big"
+	"os"
+	"smart-contract-service/models"
+)
+
+// size of a BN254 base field element in the raw proof encoding
+const fpSize = 32
+
+// SolidityExporter is implemented by the groth16 and plonk verifying keys
+type SolidityExporter interface {
+	ExportSolidity(w io.Writer) error
+}
+
+// ExportSolidity writes the solidity Verifier contract of a BN254 verifying key to given file
+func ExportSolidity(vk SolidityExporter, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return vk.ExportSolidity(f)
+}
+
+// ProofCalldata splits a BN254 groth16 proof and its public witness into the
+// (a, b, c, input) arguments of the exported Verifier.verifyProof
+func ProofCalldata(proof groth16.Proof, publicWitness witness.Witness) (*models.ProofCalldata, error) {
+	if proof.CurveID() != ecc.BN254 {
+		return nil, fmt.Errorf("solidity calldata not supported on curve %s", proof.CurveID())
+	}
+
+	// raw encoding is Ar | Bs | Krs, uncompressed, G2 coordinates as A1 | A0
+	var buf bytes.Buffer
+	if _, err := proof.WriteRawTo(&buf); err != nil {
+		return nil, err
+	}
+	raw := buf.Bytes()
+	if len(raw) < 8*fpSize {
+		return nil, errors.New("proof too short")
+	}
+	word := func(i int) string {
+		return new(big.Int).SetBytes(raw[i*fpSize : (i+1)*fpSize]).String()
+	}
+
+	public, ok := publicWitness.Vector().(fr.Vector)
+	if !ok {
+		return nil, errors.New("public witness is not a BN254 vector")
+	}
+	input := make([]string, len(public))
+	for i := range public {
+		input[i] = public[i].String()
+	}
+
+	return &models.ProofCalldata{
+		A:     [2]string{word(0), word(1)},
+		B:     [2][2]string{{word(2), word(3)}, {word(4), word(5)}},
+		C:     [2]string{word(6), word(7)},
+		Input: input,
+	}, nil
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend/groth16"
-	"github.com/consensys/gnark/frontend"
-	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/redis/go-redis/v9"
@@ -83,6 +84,27 @@ func setWebRouter(config configuration.ConfigApp) *echo.Echo {
 	e.Use(middleware.RequestID())
 	e.Use(middlewareLogging)
 
+	var (
+		migrate bool
+		init    bool
+		force   bool
+	)
+	flag.BoolVar(&migrate, "migrate", true, "If migrate true")
+	flag.BoolVar(&init, "init", false, "set to true to run circuit Setup and export solidity Verifier")
+	flag.BoolVar(&force, "force", false, "set with -init to replace the keys of a trusted setup ceremony")
+	flag.Parse()
+
+	// customer signing keys are never sealed with the token secret
+	if config.KeyEncryptionKey == "" || config.KeyEncryptionKey == config.Secret {
+		log.Fatal("KEY_ENCRYPTION_KEY must be set apart from SECRET to seal customer signing keys")
+	}
+
+	configureCircuits(config)
+	if init {
+		initCircuits(config, force)
+		initIssuerKey(config)
+	}
+
 	dbConn := configuration.InitSingleDB(config.PostgreConnection, config.LogMode)
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     config.RedisConnection,
@@ -91,30 +113,48 @@ func setWebRouter(config configuration.ConfigApp) *echo.Echo {
 	})
 	repoDb := repo.NewDatabaseConnection(dbConn)
 	repoRedis := repo.NewRedisConnection(redisClient)
+	keyStore := repo.NewKeyStore(config)
+	jobQueue := repo.NewLocalJobQueue(config.ProofJobQueueSize, config.ProofJobWorkers)
+	membershipTree := repo.NewMembershipTree(config.MembershipRootHistory)
 
-	uc := usecase.NewUsecase(repoRedis, repoDb, config)
+	uc := usecase.NewUsecase(repoRedis, repoDb, keyStore, jobQueue, membershipTree, config)
 
 	handler := web.NewHTTP(config, uc)
-	var (
-		migrate bool
-		init    bool
-	)
-	flag.BoolVar(&migrate, "migrate", true, "If migrate true")
-	flag.BoolVar(&init, "init", false, "set to true to run circuit Setup and export solidity Verifier")
-	flag.Parse()
 
 	if migrate {
 		dbConn.AutoMigrate(
-			&models.Partners{}, // create table partners
-			&models.Customer{}, // create table customers
-			&models.Payment{},  // create table payment
+			&models.Partners{},       // create table partners
+			&models.Customer{},       // create table customers
+			&models.Payment{},        // create table payment
+			&models.CustomerKey{},    // create table customer_keys
+			&models.ProofJob{},       // create table proof_jobs
+			&models.SpentNullifier{}, // create table spent_nullifiers
 		)
 	}
 
-	if init {
-		initElliptic()
-		initHash()
-		initEddsa()
+	// load circuit keys once, refuse to start on missing or corrupt artifacts
+	if err := keyStore.Load(); err != nil {
+		log.WithField("error", err).Error("Unable to load circuit keys")
+		os.Exit(1)
+	}
+	log.Println("circuit keys loaded")
+	go func() {
+		if err := keyStore.LoadAggregators(); err != nil {
+			log.WithField("error", err).Error("Unable to load aggregator keys")
+			return
+		}
+		log.Println("aggregator keys loaded")
+	}()
+	if config.KeyReloadInterval > 0 {
+		go keyStore.Watch(time.Duration(config.KeyReloadInterval) * time.Second)
+	}
+	if err := uc.StartProofJobs(); err != nil {
+		log.WithField("error", err).Error("Unable to resume proof jobs")
+		os.Exit(1)
+	}
+	if err := uc.StartMembership(); err != nil {
+		log.WithField("error", err).Error("Unable to build the membership tree")
+		os.Exit(1)
 	}
 
 	web.NewRoutes(config).RegisterServices(e, handler)
@@ -122,76 +162,180 @@ func setWebRouter(config configuration.ConfigApp) *echo.Echo {
 	return e
 }
 
-func initHash() {
-	var circuit models2.Circuit
+// configureCircuits moves circuits to their configured curve, before any of their keys
+// is set up or loaded
+func configureCircuits(config configuration.ConfigApp) {
+	if err := models2.Configure(config.CircuitCurves); err != nil {
+		log.WithField("error", err).Fatal("Unable to configure the circuit curves")
+	}
+}
 
-	// compile circuit
-	log.Println("compiling circuit")
-	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &circuit)
-	assertNoError(err)
+// initCircuits sets up every registered circuit for each of its backends and writes the manifest.
+// The groth16 keys it samples are only fit for development, production keys come out of
+// the ceremony subcommands and are kept by a new -init unless force is set.
+func initCircuits(config configuration.ConfigApp, force bool) {
+	type setup struct {
+		def *models2.Definition
+		b   backend.ID
+		ccs constraint.ConstraintSystem
+	}
 
-	// run groth16 trusted setup
-	log.Println("running groth16.Setup")
-	pk, vk, err := groth16.Setup(r1cs)
-	assertNoError(err)
+	// compile first, the plonk srs of each curve must fit its largest circuit
+	var setups []setup
+	srsSizes := make(map[ecc.ID]uint64)
+	for _, def := range models2.Definitions() {
+		for _, b := range def.Backends() {
+			log.Println("compiling circuit", def.Name, b, def.Curve)
+			ccs, err := models2.Compile(def.Curve, b, def.Circuit())
+			assertNoError(err)
+			setups = append(setups, setup{def: def, b: b, ccs: ccs})
+			if b == backend.PLONK && models2.SRSSize(ccs) > srsSizes[def.Curve] {
+				srsSizes[def.Curve] = models2.SRSSize(ccs)
+			}
+		}
+	}
 
-	// serialize R1CS, proving & verifying key
-	log.Println("serialize R1CS (circuit)", internal.R1csPath)
-	internal.Serialize(r1cs, internal.R1csPath)
+	srs := make(map[ecc.ID]kzg.SRS)
+	for curve, size := range srsSizes {
+		srs[curve] = initSRS(curve, size)
+	}
 
-	log.Println("serialize proving key", internal.PkPath)
-	internal.Serialize(pk, internal.PkPath)
+	previous := readPreviousManifest(config, force)
+	manifest := &models2.Manifest{}
+	for _, s := range setups {
+		if entry := ceremonyEntry(previous, s.def, s.b, s.ccs, force); entry != nil {
+			log.Println("keeping ceremony keys", s.def.Name, s.b, entry.Ceremony)
+			manifest.Circuits = append(manifest.Circuits, *entry)
+			continue
+		}
+		manifest.Circuits = append(manifest.Circuits, initCircuit(s.def, s.b, s.ccs, srs[s.def.Curve]))
+	}
+	writeManifest(config, manifest)
+}
 
-	log.Println("serialize verifying key", internal.VkPath)
-	internal.Serialize(vk, internal.VkPath)
+// readPreviousManifest returns the manifest a new -init replaces, nil when there is none or
+// force is set. Its ceremony entries are carried over so it must carry a valid signature.
+func readPreviousManifest(config configuration.ConfigApp, force bool) *models2.Manifest {
+	if force {
+		return nil
+	}
+	manifest, err := models2.ReadManifest(internal.ManifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	assertNoError(err)
+	publicKey, err := internal.GeneratePublicKey(config)
+	assertNoError(err)
+	if err = manifest.Verify(publicKey); err != nil {
+		log.Fatalf("%s, pass -force to replace every artifact : %s", internal.ManifestPath, err)
+	}
+	return manifest
 }
 
-func initElliptic() {
-	var elliptic models2.EllipticCurve
+// ceremonyEntry returns the previous entry of the circuit when its keys come out of a
+// ceremony, so they are not overwritten by development keys. A circuit that changed since
+// the ceremony needs a new one, -init refuses to go on unless force is set.
+func ceremonyEntry(previous *models2.Manifest, def *models2.Definition, b backend.ID, ccs constraint.ConstraintSystem, force bool) *models2.ManifestEntry {
+	if previous == nil || force {
+		return nil
+	}
+	entry, err := previous.Entry(def.Name, b)
+	if err != nil || entry.Ceremony == "" {
+		return nil
+	}
+	if entry.Constraints != ccs.GetNbConstraints() {
+		err = fmt.Errorf("%d constraints, the ceremony was run for %d", ccs.GetNbConstraints(), entry.Constraints)
+	} else {
+		err = entry.Check(def, b)
+	}
+	if err != nil {
+		log.Fatalf("circuit %s (%s) has ceremony keys %s that no longer fit : %s, run a new ceremony or pass -force to replace them", def.Name, b, entry.Ceremony, err)
+	}
+	return entry
+}
 
-	// compile circuit
-	log.Println("compiling elliptic circuit")
-	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &elliptic)
+// initSRS reads the universal srs of the curve, a new one is only sampled when none fits
+// the circuits so plonk keys can be derived again after a circuit change without a new setup
+func initSRS(curve ecc.ID, size uint64) kzg.SRS {
+	srsPath := models2.SRSPath(curve)
+	srs, err := models2.NewEmptySRS(curve)
 	assertNoError(err)
+	if err = internal.Deserialize(srs, srsPath); err == nil && uint64(models2.SRSLen(srs)) >= size {
+		log.Println("using kzg srs", srsPath)
+		return srs
+	}
 
-	// run groth16 trusted setup
-	log.Println("running groth16.Setup")
-	pk, vk, err := groth16.Setup(r1cs)
+	log.Println("sampling kzg srs of size", size, "on", curve)
+	srs, err = models2.NewSRS(curve, size)
 	assertNoError(err)
+	err = internal.Serialize(srs, srsPath)
+	assertNoError(err)
+	return srs
+}
 
-	// serialize R1CS, proving & verifying key
-	log.Println("serialize R1CS (circuit)", internal.R1csEllipticPath)
-	internal.Serialize(r1cs, internal.R1csEllipticPath)
+func initCircuit(def *models2.Definition, b backend.ID, ccs constraint.ConstraintSystem, srs kzg.SRS) models2.ManifestEntry {
+	artifact := def.Artifact.For(b)
 
-	log.Println("serialize proving key", internal.PkEllipticPath)
-	internal.Serialize(pk, internal.PkEllipticPath)
+	// run the backend setup
+	log.Println("running setup", def.Name, b)
+	keys, err := models2.Setup(def.Curve, b, ccs, srs)
+	assertNoError(err)
 
-	log.Println("serialize verifying key", internal.VkEllipticPath)
-	internal.Serialize(vk, internal.VkEllipticPath)
-}
+	// serialize constraint system, proving & verifying key
+	log.Println("serialize constraint system (circuit)", artifact.R1cs)
+	err = internal.Serialize(ccs, artifact.R1cs)
+	assertNoError(err)
+
+	log.Println("serialize proving key", artifact.Pk)
+	err = internal.Serialize(keys.Pk, artifact.Pk)
+	assertNoError(err)
+
+	log.Println("serialize verifying key", artifact.Vk)
+	err = internal.Serialize(keys.Vk, artifact.Vk)
+	assertNoError(err)
 
-func initEddsa() {
-	var eddsa models2.EddsaCircuit
+	// export solidity verifier, gnark only has one for BN254 verifying keys
+	if def.Curve == ecc.BN254 {
+		log.Println("export solidity verifier", artifact.Solidity)
+		err = internal.ExportSolidity(keys.Vk, artifact.Solidity)
+		assertNoError(err)
+	}
 
-	// compile circuit
-	log.Println("compiling eddsa circuit")
-	r1cs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs2.NewBuilder, &eddsa)
+	entry, err := models2.NewManifestEntry(def, b, ccs.GetNbConstraints())
 	assertNoError(err)
+	return entry
+}
 
-	// run groth16 trusted setup
-	log.Println("running groth16.Setup")
-	pk, vk, err := groth16.Setup(r1cs)
+func writeManifest(config configuration.ConfigApp, manifest *models2.Manifest) {
+	// sign the manifest with the service key, checked again on every startup
+	privKey, err := internal.GeneratePrivateKey(config)
+	assertNoError(err)
+	err = manifest.Sign(privKey)
 	assertNoError(err)
 
-	// serialize R1CS, proving & verifying key
-	log.Println("serialize R1CS (circuit)", internal.R1csEddsaPath)
-	internal.Serialize(r1cs, internal.R1csEddsaPath)
+	log.Println("write circuit manifest", internal.ManifestPath)
+	err = models2.WriteManifest(manifest, internal.ManifestPath)
+	assertNoError(err)
+}
 
-	log.Println("serialize proving key", internal.PkEddsaPath)
-	internal.Serialize(pk, internal.PkEddsaPath)
+// initIssuerKey creates the credential issuer key, an existing key is kept so issued
+// credentials stay valid unless it was revoked
+func initIssuerKey(config configuration.ConfigApp) {
+	_, err := models2.ReadIssuerKey(config.IssuerKeyLocation)
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, models2.ErrIssuerKeyRevoked):
+		log.Warnf("rotate revoked credential issuer key %s", config.IssuerKeyLocation)
+	case !errors.Is(err, os.ErrNotExist):
+		assertNoError(err)
+	}
+	key, err := models2.GenerateSigningKey()
+	assertNoError(err)
 
-	log.Println("serialize verifying key", internal.VkEddsaPath)
-	internal.Serialize(vk, internal.VkEddsaPath)
+	log.Println("write credential issuer key", config.IssuerKeyLocation)
+	err = models2.WriteIssuerKey(key, config.IssuerKeyLocation)
+	assertNoError(err)
 }
 
 func assertNoError(err error) {
@@ -201,5 +345,9 @@ func assertNoError(err error) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ceremony" {
+		runCeremony(os.Args[2:])
+		return
+	}
 	configAndStartServer()
 }
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
@@ -33,15 +34,11 @@ func AccessTokenValidator(cfg configuration.ConfigApp) echo.MiddlewareFunc {
 }
 
 func validateJWTtoken(auth string, cfg configuration.ConfigApp) (data models.JwtCustomClaims, err error) {
-	if len(auth) == 0 {
-		return
-	}
-
-	authorization := strings.Split(auth, "Bearer ")
-	if len(authorization) < 0 {
-		return
+	// a request without a bearer token is not authenticated, whatever route it is on
+	tokenString := strings.TrimPrefix(auth, "Bearer ")
+	if tokenString == "" || tokenString == auth {
+		return data, errors.New("missing bearer access token")
 	}
-	tokenString := authorization[1]
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Don't forget to validate the alg is what you expect:
@@ -55,13 +52,16 @@ func validateJWTtoken(auth string, cfg configuration.ConfigApp) (data models.Jwt
 		return
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		data.ID = claims["id"].(string)
-		data.Username = claims["username"].(string)
-		data.LoginAt = claims["loginAt"].(string)
-		data.ExpireAt = claims["expireAt"].(string)
-	} else {
-		return
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return data, errors.New("invalid access token")
+	}
+	data.ID, _ = claims["id"].(string)
+	data.Username, _ = claims["username"].(string)
+	data.LoginAt, _ = claims["loginAt"].(string)
+	data.ExpireAt, _ = claims["expireAt"].(string)
+	if data.ID == "" {
+		return data, errors.New("access token names no partner")
 	}
 	return
 }
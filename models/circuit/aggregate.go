@@ -0,0 +1,205 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/sw_bls12377"
+	"github.com/consensys/gnark/std/groth16_bls12377"
+	"math/big"
+	"path"
+	"strings"
+)
+
+// AggregateSuffix names the aggregator of a circuit after the circuit
+const AggregateSuffix = "-aggregate"
+
+var ErrAggregateNotSupported = errors.New("proofs of the circuit cannot be aggregated")
+
+// AggregateCircuit verifies Groth16 proofs of a BLS12-377 circuit inside a BW6-761
+// circuit, so its proof stands for all of them. The verifying key of the circuit is a
+// public input, the aggregator only depends on the shape of the circuit and the key is
+// checked against the published one when verifying. Count proofs are folded, the slots
+// after them repeat the public inputs of the last one.
+type AggregateCircuit struct {
+	Vk     groth16_bls12377.VerifyingKey `gnark:",public"`
+	Public [][]frontend.Variable         `gnark:",public"`
+	Count  frontend.Variable             `gnark:",public"`
+	Proofs []groth16_bls12377.Proof
+}
+
+func (circuit *AggregateCircuit) Define(api frontend.API) error {
+	// slot i is padding when the count is at most i, the count is one of 1..size
+	var padding frontend.Variable = 0
+	for i := range circuit.Proofs {
+		groth16_bls12377.Verify(api, circuit.Vk, circuit.Proofs[i], circuit.Public[i])
+		if i == 0 {
+			continue
+		}
+		padding = api.Add(padding, api.IsZero(api.Sub(circuit.Count, i)))
+		for j := range circuit.Public[i] {
+			api.AssertIsEqual(api.Mul(padding, api.Sub(circuit.Public[i][j], circuit.Public[i-1][j])), 0)
+		}
+	}
+	api.AssertIsEqual(api.Add(padding, api.IsZero(api.Sub(circuit.Count, len(circuit.Proofs)))), 1)
+	return nil
+}
+
+// newAggregateCircuit returns an empty aggregator of size proofs of nbPublic public inputs
+func newAggregateCircuit(size, nbPublic int) *AggregateCircuit {
+	circuit := &AggregateCircuit{
+		Public: make([][]frontend.Variable, size),
+		Proofs: make([]groth16_bls12377.Proof, size),
+	}
+	// the key holds a point for the constant wire and one per public input
+	circuit.Vk.G1.K = make([]sw_bls12377.G1Affine, nbPublic+1)
+	for i := range circuit.Public {
+		circuit.Public[i] = make([]frontend.Variable, nbPublic)
+	}
+	return circuit
+}
+
+// newAggregator returns the definition of the circuit folding def.Aggregate proofs of def
+func newAggregator(def *Definition) (*Definition, error) {
+	if def.Curve != ecc.BLS12_377 || def.Backend != backend.GROTH16 {
+		return nil, fmt.Errorf("%w : aggregated proofs must be groth16 on %s", ErrAggregateNotSupported, ecc.BLS12_377)
+	}
+	schema, err := frontend.NewSchema(def.Circuit())
+	if err != nil {
+		return nil, err
+	}
+	size, nbPublic := def.Aggregate, schema.NbPublic
+	base := strings.TrimSuffix(def.Artifact.R1cs, path.Ext(def.Artifact.R1cs)) + AggregateSuffix
+	return &Definition{
+		Name:       def.Name + AggregateSuffix,
+		Circuit:    func() frontend.Circuit { return newAggregateCircuit(size, nbPublic) },
+		Artifact:   artifactAt(base),
+		Curve:      ecc.BW6_761,
+		Backend:    backend.GROTH16,
+		Aggregate:  size,
+		Aggregates: def,
+	}, nil
+}
+
+// AssignAggregate builds the aggregator witness of proofs verified with vk, fewer proofs
+// than the aggregator size are padded with the last one
+func AssignAggregate(agg *Definition, vk VerifyingKey, proofs []Proof, publicWitnesses []witness.Witness) (frontend.Circuit, error) {
+	if agg.Aggregates == nil {
+		return nil, fmt.Errorf("%w : %s is not an aggregator", ErrAggregateNotSupported, agg.Name)
+	}
+	if len(proofs) == 0 || len(proofs) > agg.Aggregate || len(proofs) != len(publicWitnesses) {
+		return nil, fmt.Errorf("%w : %d proofs for an aggregator of %d", ErrAggregateNotSupported, len(proofs), agg.Aggregate)
+	}
+	innerVk, ok := vk.(groth16.VerifyingKey)
+	if !ok || innerVk.NbPublicWitness()+1 != len(agg.Circuit().(*AggregateCircuit).Vk.G1.K) {
+		return nil, fmt.Errorf("%w : verifying key does not match %s", ErrAggregateNotSupported, agg.Name)
+	}
+
+	assignment := &AggregateCircuit{
+		Public: make([][]frontend.Variable, agg.Aggregate),
+		Proofs: make([]groth16_bls12377.Proof, agg.Aggregate),
+	}
+	assignment.Vk.Assign(innerVk)
+	assignment.Count = len(proofs)
+	for i := range assignment.Proofs {
+		j := i
+		if j >= len(proofs) {
+			j = len(proofs) - 1
+		}
+		if err := assignProof(&assignment.Proofs[i], proofs[j]); err != nil {
+			return nil, err
+		}
+		values, err := publicValues(publicWitnesses[j])
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != innerVk.NbPublicWitness() {
+			return nil, fmt.Errorf("%w : %d public inputs, expected %d", ErrAggregateNotSupported, len(values), innerVk.NbPublicWitness())
+		}
+		for _, v := range values {
+			assignment.Public[i] = append(assignment.Public[i], v)
+		}
+	}
+	return assignment, nil
+}
+
+// assignProof reads the points of a BLS12-377 groth16 proof from its encoding, gnark does
+// not expose the proof type of the curve
+func assignProof(p *groth16_bls12377.Proof, proof Proof) error {
+	var buf bytes.Buffer
+	if _, err := proof.WriteTo(&buf); err != nil {
+		return err
+	}
+	var ar, krs bls12377.G1Affine
+	var bs bls12377.G2Affine
+	dec := bls12377.NewDecoder(&buf)
+	for _, point := range []interface{}{&ar, &bs, &krs} {
+		if err := dec.Decode(point); err != nil {
+			return fmt.Errorf("%w : %s", ErrAggregateNotSupported, err.Error())
+		}
+	}
+	p.Ar.Assign(&ar)
+	p.Bs.Assign(&bs)
+	p.Krs.Assign(&krs)
+	return nil
+}
+
+// CheckAggregatePublic verifies the public witness of an aggregated proof carries the
+// verifying key of the circuit and returns the public inputs of each folded proof, the
+// padding is left out
+func CheckAggregatePublic(agg *Definition, vk VerifyingKey, publicWitness witness.Witness) ([][]*big.Int, error) {
+	innerVk, ok := vk.(groth16.VerifyingKey)
+	if agg.Aggregates == nil || !ok {
+		return nil, fmt.Errorf("%w : %s is not an aggregator", ErrAggregateNotSupported, agg.Name)
+	}
+
+	// the key is first in the public witness, followed by the inputs of every proof
+	expected := newAggregateCircuit(agg.Aggregate, innerVk.NbPublicWitness())
+	expected.Vk.Assign(innerVk)
+	for i := range expected.Public {
+		for j := range expected.Public[i] {
+			expected.Public[i][j] = 0
+		}
+	}
+	expected.Count = 0
+	expectedWitness, err := frontend.NewWitness(expected, agg.Curve.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		return nil, err
+	}
+	keyValues, err := publicValues(expectedWitness)
+	if err != nil {
+		return nil, err
+	}
+	values, err := publicValues(publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	nbKey := len(keyValues) - agg.Aggregate*innerVk.NbPublicWitness() - 1
+	if len(values) != len(keyValues) {
+		return nil, fmt.Errorf("%d public inputs, expected %d", len(values), len(keyValues))
+	}
+	for i := 0; i < nbKey; i++ {
+		if values[i].Cmp(keyValues[i]) != 0 {
+			return nil, fmt.Errorf("proofs were not verified with the verifying key of %s", agg.Aggregates.Name)
+		}
+	}
+
+	// the aggregator proves the count is one of 1..size
+	count := values[len(values)-1]
+	if count.Sign() <= 0 || count.Cmp(big.NewInt(int64(agg.Aggregate))) > 0 {
+		return nil, fmt.Errorf("%s proofs folded, expected 1 to %d", count, agg.Aggregate)
+	}
+
+	public := make([][]*big.Int, count.Int64())
+	for i := range public {
+		offset := nbKey + i*innerVk.NbPublicWitness()
+		public[i] = values[offset : offset+innerVk.NbPublicWitness()]
+	}
+	return public, nil
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"smart-contract-service/models"
+	"testing"
+)
+
+// paymentProofs proves a payment of each amount on the circuit folded by the aggregator
+func paymentProofs(t *testing.T, agg *Definition, amounts ...string) (*Keys, []Proof, []witness.Witness) {
+	t.Helper()
+	def := agg.Aggregates
+	ccs, err := Compile(def.Curve, backend.GROTH16, def.Circuit())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := Setup(def.Curve, backend.GROTH16, ccs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt, err := GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	customer := &models.Customer{Id: "cust-1", KTP: "3171", NoRek: "123", MotherName: "Siti", CommitmentSalt: salt}
+	if customer.Commitment, err = Commitment(CommitmentCurve, customer, salt); err != nil {
+		t.Fatal(err)
+	}
+
+	var proofs []Proof
+	var publicWitnesses []witness.Witness
+	for _, amount := range amounts {
+		intent := &models.PaymentIntent{PartnerReferenceNo: "ref-1", Amount: amount, Currency: "IDR", ExternalId: "ext-1"}
+		assignment, err := def.Assign(&WitnessInput{Customer: customer, Curve: def.Curve, Intent: intent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fullWitness, err := frontend.NewWitness(assignment, def.Curve.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := keys.Prove(fullWitness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicWitness, err := fullWitness.Public()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proofs, publicWitnesses = append(proofs, proof), append(publicWitnesses, publicWitness)
+	}
+	return keys, proofs, publicWitnesses
+}
+
+func aggregatePublic(t *testing.T, agg *Definition, assignment frontend.Circuit) witness.Witness {
+	t.Helper()
+	publicWitness, err := frontend.NewWitness(assignment, agg.Curve.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicWitness
+}
+
+func TestAssignAggregate(t *testing.T) {
+	agg, err := LookupAggregator(PaymentBLS12377Algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, proofs, publicWitnesses := paymentProofs(t, agg, "10.00", "20.00", "30.00")
+	ccs, err := Compile(agg.Curve, agg.Backend, agg.Circuit())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{1, 2} {
+		assignment, err := AssignAggregate(agg, keys.Vk, proofs[:n], publicWitnesses[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fullWitness, err := frontend.NewWitness(assignment, agg.Curve.ScalarField())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ccs.IsSolved(fullWitness); err != nil {
+			t.Fatalf("%d proofs do not solve the aggregator: %v", n, err)
+		}
+		// a single proof is padded with itself up to the aggregator size
+		if len(assignment.(*AggregateCircuit).Public) != agg.Aggregate {
+			t.Fatalf("%d proofs assigned to %d slots", n, len(assignment.(*AggregateCircuit).Public))
+		}
+		public, err := CheckAggregatePublic(agg, keys.Vk, aggregatePublic(t, agg, assignment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(public) != n {
+			t.Fatalf("%d proofs folded, %d read back", n, len(public))
+		}
+		for i := range public {
+			values, _ := publicValues(publicWitnesses[i])
+			for j := range values {
+				if public[i][j].Cmp(values[j]) != 0 {
+					t.Fatalf("public input %d of proof %d read as %s, expected %s", j, i, public[i][j], values[j])
+				}
+			}
+		}
+	}
+
+	// a count below the proofs would pass a folded proof off as padding
+	assignment, err := AssignAggregate(agg, keys.Vk, proofs[:2], publicWitnesses[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignment.(*AggregateCircuit).Count = 1
+	fullWitness, err := frontend.NewWitness(assignment, agg.Curve.ScalarField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ccs.IsSolved(fullWitness); err == nil {
+		t.Fatal("aggregator solved with a proof hidden as padding")
+	}
+
+	for name, c := range map[string]struct {
+		proofs []Proof
+		public []witness.Witness
+	}{
+		"no proof":           {},
+		"more than the size": {proofs, publicWitnesses},
+		"missing witness":    {proofs[:2], publicWitnesses[:1]},
+	} {
+		if _, err = AssignAggregate(agg, keys.Vk, c.proofs, c.public); !errors.Is(err, ErrAggregateNotSupported) {
+			t.Fatalf("%s assigned with %v", name, err)
+		}
+	}
+	if _, err = AssignAggregate(agg.Aggregates, keys.Vk, proofs[:1], publicWitnesses[:1]); !errors.Is(err, ErrAggregateNotSupported) {
+		t.Fatalf("circuit assigned as an aggregator with %v", err)
+	}
+}
+
+func TestCheckAggregatePublic(t *testing.T) {
+	agg, err := LookupAggregator(PaymentBLS12377Algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, proofs, publicWitnesses := paymentProofs(t, agg, "10.00")
+	assignment, err := AssignAggregate(agg, keys.Vk, proofs, publicWitnesses)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, _ := paymentProofs(t, agg)
+	if _, err = CheckAggregatePublic(agg, other.Vk, aggregatePublic(t, agg, assignment)); err == nil {
+		t.Fatal("proofs of another verifying key checked")
+	}
+	for _, count := range []int{0, agg.Aggregate + 1} {
+		assignment.(*AggregateCircuit).Count = count
+		if _, err = CheckAggregatePublic(agg, keys.Vk, aggregatePublic(t, agg, assignment)); err == nil {
+			t.Fatalf("count %d checked", count)
+		}
+	}
+	if _, err = CheckAggregatePublic(agg.Aggregates, keys.Vk, publicWitnesses[0]); !errors.Is(err, ErrAggregateNotSupported) {
+		t.Fatalf("circuit checked as an aggregator with %v", err)
+	}
+}
@@ -1,13 +1,59 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
 	mimc2 "github.com/consensys/gnark/std/hash/mimc"
+	"math/big"
+	"smart-contract-service/models"
 )
 
+const HashAlgorithm = "hash"
+
+// CommitmentCurve is the curve commitments are enrolled on, the membership tree and the
+// credentials are built over it. Circuits on another curve commit to the same attributes
+// and salt on their own curve.
+const CommitmentCurve = ecc.BN254
+
+var (
+	ErrCommitmentMissing  = errors.New("no commitment enrolled for customer")
+	ErrCommitmentMismatch = errors.New("commitment does not match the enrolled commitment")
+)
+
+func init() {
+	Register(&Definition{
+		Name:        HashAlgorithm,
+		Circuit:     func() frontend.Circuit { return &Circuit{} },
+		Assign:      assignHash,
+		CheckPublic: checkHashPublic,
+		Nullifier:   hashNullifier,
+		Inputs:      hashInputs,
+		Artifact:    NewArtifact("mimc"),
+		Curves:      mimcCurves,
+	})
+}
+
+var hashInputs = []PublicInput{
+	{Name: "Hash", Description: "commitment enrolled on the customer"},
+	{Name: "Context", Description: "what the proof is made for, as a field element"},
+	{Name: "Nullifier", Description: "spent once per context, unlinkable to the customer"},
+}
+
+// Circuit proves knowledge of the customer attributes behind the commitment
+// enrolled on the customers row. The nullifier is derived from the salt, only known
+// to the customer, and the context the proof is made for, so a context is proved once.
 type Circuit struct {
-	Secret frontend.Variable
-	Hash   frontend.Variable `gnark:",public"`
+	KTP        frontend.Variable
+	Account    frontend.Variable
+	MotherName frontend.Variable
+	Salt       frontend.Variable
+	Hash       frontend.Variable `gnark:",public"`
+	Context    frontend.Variable `gnark:",public"`
+	Nullifier  frontend.Variable `gnark:",public"`
 }
 
 func (circuit *Circuit) Define(api frontend.API) error {
@@ -16,7 +62,163 @@ func (circuit *Circuit) Define(api frontend.API) error {
 	if err != nil {
 		return err
 	}
-	mimc.Write(circuit.Secret)
+	mimc.Write(circuit.KTP, circuit.Account, circuit.MotherName, circuit.Salt)
 	api.AssertIsEqual(circuit.Hash, mimc.Sum())
+
+	mimc.Reset()
+	mimc.Write(circuit.Salt, circuit.Context)
+	api.AssertIsEqual(circuit.Nullifier, mimc.Sum())
+	return nil
+}
+
+// GenerateSalt returns a hex encoded random salt, 31 bytes so it is always a field element
+func GenerateSalt() (string, error) {
+	salt := make([]byte, 31)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// Commitment returns the decimal MiMC commitment over the customer KTP, account
+// number and mother name, blinded by the salt, on the scalar field of the curve
+func Commitment(curve ecc.ID, cData *models.Customer, salt string) (string, error) {
+	preimage, err := commitmentPreimage(curve, cData, salt)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	for _, b := range preimage {
+		data = append(data, b...)
+	}
+	return mimcHash(curve, data)
+}
+
+// enrolledCommitment returns the commitment enrolled for the customer on the curve, the
+// attributes must still open the enrolled commitment to be committed on another curve
+func enrolledCommitment(curve ecc.ID, cData *models.Customer) (string, error) {
+	if cData.Commitment == "" {
+		return "", ErrCommitmentMissing
+	}
+	if curve == CommitmentCurve {
+		return cData.Commitment, nil
+	}
+	if cData.CommitmentSalt == "" {
+		return "", ErrCommitmentMissing
+	}
+	enrolled, err := Commitment(CommitmentCurve, cData, cData.CommitmentSalt)
+	if err != nil {
+		return "", err
+	}
+	if enrolled != cData.Commitment {
+		return "", ErrCommitmentMismatch
+	}
+	return Commitment(curve, cData, cData.CommitmentSalt)
+}
+
+// commitmentPreimage encodes the committed attributes as field elements, in circuit order
+func commitmentPreimage(curve ecc.ID, cData *models.Customer, salt string) ([4][]byte, error) {
+	saltBin, err := hex.DecodeString(salt)
+	if err != nil {
+		return [4][]byte{}, err
+	}
+	saltElement := new(big.Int).SetBytes(saltBin)
+	saltElement.Mod(saltElement, curve.ScalarField())
+
+	return [4][]byte{
+		fieldBytes(curve, []byte(cData.KTP)),
+		fieldBytes(curve, []byte(cData.NoRek)),
+		fieldBytes(curve, []byte(cData.MotherName)),
+		saltElement.FillBytes(make([]byte, 32)),
+	}, nil
+}
+
+func assignHash(in *WitnessInput) (frontend.Circuit, error) {
+	// without a context the proof is made for a random nonce, it is then single use
+	context := in.Context
+	if context == "" {
+		var err error
+		if context, err = GenerateSalt(); err != nil {
+			return nil, err
+		}
+	}
+	return assignCommitment(in, fieldBytes(in.Curve, []byte(context)))
+}
+
+// assignCommitment assigns the enrolled commitment of the customer and the nullifier of
+// the context, given as a 32 bytes field element
+func assignCommitment(in *WitnessInput, context []byte) (*Circuit, error) {
+	cData := in.Customer
+	if cData.Commitment == "" || cData.CommitmentSalt == "" {
+		return nil, ErrCommitmentMissing
+	}
+
+	preimage, err := commitmentPreimage(in.Curve, cData, cData.CommitmentSalt)
+	if err != nil {
+		return nil, err
+	}
+	// attributes changed since enrolment, the customer has to enrol again
+	enrolled, err := enrolledCommitment(in.Curve, cData)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := Commitment(in.Curve, cData, cData.CommitmentSalt)
+	if err != nil {
+		return nil, err
+	}
+	if hash != enrolled {
+		return nil, ErrCommitmentMismatch
+	}
+
+	nullifier, err := mimcHash(in.Curve, append(append([]byte{}, preimage[3]...), context...))
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := &Circuit{}
+	assignment.KTP = frontend.Variable(preimage[0])
+	assignment.Account = frontend.Variable(preimage[1])
+	assignment.MotherName = frontend.Variable(preimage[2])
+	assignment.Salt = frontend.Variable(preimage[3])
+	assignment.Hash = frontend.Variable(hash)
+	assignment.Context = frontend.Variable(context)
+	assignment.Nullifier = frontend.Variable(nullifier)
+	return assignment, nil
+}
+
+// hashNullifier returns the nullifier of a verified hash witness, after the
+// commitment and the context
+func hashNullifier(publicWitness witness.Witness) (string, error) {
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return "", err
+	}
+	if len(public) != 3 {
+		return "", errors.New("public witness is not a hash witness")
+	}
+	return public[2].String(), nil
+}
+
+// checkHashPublic asserts the proven hash is the commitment enrolled for the customer
+func checkHashPublic(publicWitness witness.Witness, in *WitnessInput) error {
+	commitment, err := enrolledCommitment(in.Curve, in.Customer)
+	if err != nil {
+		return err
+	}
+	enrolled, ok := new(big.Int).SetString(commitment, 10)
+	if !ok {
+		return errors.New("enrolled commitment is not a decimal field element")
+	}
+
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return err
+	}
+	if len(public) < 1 {
+		return errors.New("public witness is not a hash witness")
+	}
+	if public[0].Cmp(enrolled) != 0 {
+		return ErrCommitmentMismatch
+	}
 	return nil
 }
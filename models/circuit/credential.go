@@ -0,0 +1,356 @@
+package models
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	eddsabn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/twistededwards"
+	mimc2 "github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/signature/eddsa"
+	"os"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	"strconv"
+	"time"
+)
+
+const (
+	CredentialAlgorithm = "credential"
+	// CredentialBranchSlots is the number of allowed branches a proof can be checked against
+	CredentialBranchSlots = 4
+
+	issuerKeyPemType = "EDDSA PRIVATE KEY"
+)
+
+var (
+	ErrIssuerKeyMissing   = errors.New("no credential issuer key")
+	ErrBirthDateMissing   = errors.New("customer has no birth date")
+	ErrCredentialMissing  = errors.New("no credential for customer")
+	ErrCredentialMismatch = errors.New("credential does not match the customer or policy")
+	ErrPolicyNotSatisfied = errors.New("customer does not satisfy the credential policy")
+)
+
+var ErrIssuerKeyRevoked = errors.New("credential issuer key was exposed, run -init to rotate it")
+
+// revokedIssuers are the hex public keys of exposed issuer keys, the service neither
+// issues nor accepts credentials signed with them
+var revokedIssuers = []string{
+	// the development key distributed with the sources, rotated
+	"f12c2646127c994446a074aa44883bdbfda4c6e62cf79a7a892dd93e2a1e589a",
+}
+
+func init() {
+	Register(&Definition{
+		Name:        CredentialAlgorithm,
+		Circuit:     func() frontend.Circuit { return &CredentialCircuit{} },
+		Assign:      assignCredential,
+		CheckPublic: checkCredentialPublic,
+		Artifact:    NewArtifact("credential"),
+		// the signatures are on the twisted Edwards curve embedded in BN254
+		Curve: ecc.BN254,
+		// proved with plonk, groth16 proofs issued before the switch still verify
+		Backend: backend.PLONK,
+		Legacy:  []backend.ID{backend.GROTH16},
+		Inputs: []PublicInput{
+			{Name: "Issuer_A_X", Description: "x of the issuer EdDSA public key"},
+			{Name: "Issuer_A_Y", Description: "y of the issuer EdDSA public key"},
+			{Name: "Commitment", Description: "commitment enrolled on the customer"},
+			{Name: "Today", Type: InputDate, Description: "date the age is computed at"},
+			{Name: "MinAge", Type: InputUint64, Description: "least age of the customer in years"},
+			{Name: "CheckBranch", Type: InputBool, Description: "whether the customer branch is checked"},
+			{Name: "Branches", Description: "field encoding of the branches allowed, unused slots repeat the first"},
+		},
+	})
+}
+
+// CredentialCircuit proves a customer holding a credential signed by the issuer is
+// at least MinAge years old at Today and, when CheckBranch is set, belongs to one
+// of the allowed branches. Birth date and branch stay private.
+type CredentialCircuit struct {
+	Issuer      eddsa.PublicKey                          `gnark:",public"`
+	Commitment  frontend.Variable                        `gnark:",public"`
+	Today       frontend.Variable                        `gnark:",public"`
+	MinAge      frontend.Variable                        `gnark:",public"`
+	CheckBranch frontend.Variable                        `gnark:",public"`
+	Branches    [CredentialBranchSlots]frontend.Variable `gnark:",public"`
+	BirthDate   frontend.Variable
+	Branch      frontend.Variable
+	Signature   eddsa.Signature
+}
+
+func (circuit *CredentialCircuit) Define(api frontend.API) error {
+	curve, err := twistededwards.NewEdCurve(api, tedwards.BN254)
+	if err != nil {
+		return err
+	}
+
+	mimc, err := mimc2.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+
+	// the issuer signed the attributes bound to the customer commitment
+	mimc.Write(circuit.Commitment, circuit.BirthDate, circuit.Branch)
+	message := mimc.Sum()
+	mimc.Reset()
+	if err = eddsa.Verify(curve, circuit.Signature, message, circuit.Issuer, &mimc); err != nil {
+		return err
+	}
+
+	// dates are yyyymmdd so adding MinAge years is adding MinAge*10000
+	api.AssertIsLessOrEqual(api.Add(circuit.BirthDate, api.Mul(circuit.MinAge, 10000)), circuit.Today)
+
+	// the branch is a root of the allowed set, unless the check is disabled
+	api.AssertIsBoolean(circuit.CheckBranch)
+	product := frontend.Variable(1)
+	for _, branch := range circuit.Branches {
+		product = api.Mul(product, api.Sub(circuit.Branch, branch))
+	}
+	api.AssertIsEqual(api.Mul(circuit.CheckBranch, product), 0)
+	return nil
+}
+
+// CredentialPolicy is what the verifier requires from a credential proof
+type CredentialPolicy struct {
+	MinAge   int
+	Branches []string
+	Today    time.Time
+}
+
+// IssueCredential signs the birth date and branch of an enrolled customer
+func IssueCredential(cData *models.Customer, issuer signature.Signer) (*models.Credential, error) {
+	if cData.Commitment == "" {
+		return nil, ErrCommitmentMissing
+	}
+	if cData.BirthDate == nil {
+		return nil, ErrBirthDateMissing
+	}
+
+	credential := &models.Credential{
+		CustomerId: cData.Id,
+		Commitment: cData.Commitment,
+		BirthDate:  dateInt(*cData.BirthDate),
+		Branch:     cData.Branch,
+		Issuer:     hex.EncodeToString(issuer.Public().Bytes()),
+	}
+	message, err := credentialMessage(credential)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := issuer.Sign(message, bn254.NewMiMC())
+	if err != nil {
+		return nil, err
+	}
+	credential.Signature = hex.EncodeToString(sig)
+	return credential, nil
+}
+
+// credentialMessage is the MiMC hash of the signed attributes, in circuit order
+func credentialMessage(credential *models.Credential) ([]byte, error) {
+	var commitment fr.Element
+	if _, err := commitment.SetString(credential.Commitment); err != nil {
+		return nil, err
+	}
+	var birthDate fr.Element
+	birthDate.SetUint64(uint64(credential.BirthDate))
+
+	commitmentBytes := commitment.Bytes()
+	birthDateBytes := birthDate.Bytes()
+
+	f := bn254.NewMiMC()
+	f.Write(commitmentBytes[:])
+	f.Write(birthDateBytes[:])
+	f.Write(internal.FieldBytes([]byte(credential.Branch)))
+	return f.Sum(nil), nil
+}
+
+// branchSlots encodes the allowed branches, unused slots repeat the first branch
+func (p *CredentialPolicy) branchSlots() (slots [CredentialBranchSlots][]byte, check int, err error) {
+	if len(p.Branches) > CredentialBranchSlots {
+		return slots, 0, fmt.Errorf("%d allowed branches, at most %d supported", len(p.Branches), CredentialBranchSlots)
+	}
+	zero := make([]byte, 32)
+	for i := range slots {
+		switch {
+		case i < len(p.Branches):
+			slots[i] = internal.FieldBytes([]byte(p.Branches[i]))
+		case len(p.Branches) > 0:
+			slots[i] = slots[0]
+		default:
+			slots[i] = zero
+		}
+	}
+	if len(p.Branches) > 0 {
+		check = 1
+	}
+	return slots, check, nil
+}
+
+// allows reports whether a proof of the credential can satisfy the policy
+func (p *CredentialPolicy) allows(credential *models.Credential) bool {
+	if credential.BirthDate+p.MinAge*10000 > dateInt(p.Today) {
+		return false
+	}
+	if len(p.Branches) == 0 {
+		return true
+	}
+	for _, branch := range p.Branches {
+		if branch == credential.Branch {
+			return true
+		}
+	}
+	return false
+}
+
+func assignCredential(in *WitnessInput) (frontend.Circuit, error) {
+	if in.Policy == nil {
+		return nil, errors.New("no credential policy")
+	}
+	credential := in.Credential
+	if credential == nil {
+		// the service issues the credential on the fly when it holds the issuer key
+		if in.Issuer == nil {
+			return nil, ErrCredentialMissing
+		}
+		var err error
+		if credential, err = IssueCredential(in.Customer, in.Issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	issuer, err := hex.DecodeString(credential.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hex.DecodeString(credential.Signature)
+	if err != nil {
+		return nil, err
+	}
+	slots, check, err := in.Policy.branchSlots()
+	if err != nil {
+		return nil, err
+	}
+	if !in.Policy.allows(credential) {
+		return nil, ErrPolicyNotSatisfied
+	}
+
+	assignment := &CredentialCircuit{}
+	assignment.Issuer.Assign(tedwards.BN254, issuer)
+	assignment.Signature.Assign(tedwards.BN254, sig)
+	assignment.Commitment = frontend.Variable(credential.Commitment)
+	assignment.Today = frontend.Variable(dateInt(in.Policy.Today))
+	assignment.MinAge = frontend.Variable(in.Policy.MinAge)
+	assignment.CheckBranch = frontend.Variable(check)
+	for i := range slots {
+		assignment.Branches[i] = frontend.Variable(slots[i])
+	}
+	assignment.BirthDate = frontend.Variable(credential.BirthDate)
+	assignment.Branch = frontend.Variable(internal.FieldBytes([]byte(credential.Branch)))
+	return assignment, nil
+}
+
+// checkCredentialPublic asserts the proof was made with the service issuer key, for the
+// commitment enrolled by the customer and under the policy the service currently requires.
+// The public witness is Issuer.X, Issuer.Y, Commitment, Today, MinAge, CheckBranch, Branches.
+func checkCredentialPublic(publicWitness witness.Witness, in *WitnessInput) error {
+	if in.Issuer == nil {
+		return ErrIssuerKeyMissing
+	}
+	if in.Policy == nil {
+		return errors.New("no credential policy")
+	}
+	if in.Customer.Commitment == "" {
+		return ErrCommitmentMissing
+	}
+	public, ok := publicWitness.Vector().(fr.Vector)
+	if !ok || len(public) != 6+CredentialBranchSlots {
+		return errors.New("public witness is not a BN254 credential witness")
+	}
+
+	issuer, ok := in.Issuer.Public().(*eddsabn254.PublicKey)
+	if !ok {
+		return errors.New("issuer key is not a BN254 EdDSA key")
+	}
+	if !public[0].Equal(&issuer.A.X) || !public[1].Equal(&issuer.A.Y) {
+		return fmt.Errorf("%w : issuer", ErrCredentialMismatch)
+	}
+
+	var commitment fr.Element
+	if _, err := commitment.SetString(in.Customer.Commitment); err != nil {
+		return err
+	}
+	if !public[2].Equal(&commitment) {
+		return fmt.Errorf("%w : commitment", ErrCredentialMismatch)
+	}
+
+	// a proof made just before midnight is still accepted
+	today := dateInt(in.Policy.Today)
+	yesterday := dateInt(in.Policy.Today.AddDate(0, 0, -1))
+	if !public[3].IsUint64() || (public[3].Uint64() != uint64(today) && public[3].Uint64() != uint64(yesterday)) {
+		return fmt.Errorf("%w : date", ErrCredentialMismatch)
+	}
+	if !public[4].IsUint64() || public[4].Uint64() < uint64(in.Policy.MinAge) {
+		return fmt.Errorf("%w : age", ErrCredentialMismatch)
+	}
+
+	slots, check, err := in.Policy.branchSlots()
+	if err != nil {
+		return err
+	}
+	if !public[5].IsUint64() || public[5].Uint64() != uint64(check) {
+		return fmt.Errorf("%w : branch", ErrCredentialMismatch)
+	}
+	for i := range slots {
+		var slot fr.Element
+		slot.SetBytes(slots[i])
+		if !public[6+i].Equal(&slot) {
+			return fmt.Errorf("%w : branch", ErrCredentialMismatch)
+		}
+	}
+	return nil
+}
+
+// dateInt returns the date as yyyymmdd
+func dateInt(t time.Time) int {
+	date, _ := strconv.Atoi(t.UTC().Format("20060102"))
+	return date
+}
+
+// ReadIssuerKey reads the PEM encoded EdDSA key the service signs credentials with
+func ReadIssuerKey(fileName string) (signature.Signer, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != issuerKeyPemType {
+		return nil, errors.New("not an EdDSA private key")
+	}
+	key, err := ParseSigningKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	public := hex.EncodeToString(key.Public().Bytes())
+	for _, revoked := range revokedIssuers {
+		if public == revoked {
+			return nil, fmt.Errorf("%w : %s", ErrIssuerKeyRevoked, public)
+		}
+	}
+	return key, nil
+}
+
+// WriteIssuerKey stores an EdDSA key in the format read by ReadIssuerKey
+func WriteIssuerKey(key signature.Signer, fileName string) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: issuerKeyPemType, Bytes: key.Bytes()})
+	return os.WriteFile(fileName, data, 0600)
+}
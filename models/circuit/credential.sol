@@ -0,0 +1,335 @@
+
+// SPDX-License-Identifier: AML
+//
+// Copyright 2017 Christian Reitwiessner
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// 2019 OKIMS
+
+pragma solidity ^0.8.0;
+
+library Pairing {
+
+    uint256 constant PRIME_Q = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+
+    struct G1Point {
+        uint256 X;
+        uint256 Y;
+    }
+
+    // Encoding of field elements is: X[0] * z + X[1]
+    struct G2Point {
+        uint256[2] X;
+        uint256[2] Y;
+    }
+
+    /*
+     * @return The negation of p, i.e. p.plus(p.negate()) should be zero.
+     */
+    function negate(G1Point memory p) internal pure returns (G1Point memory) {
+
+        // The prime q in the base field F_q for G1
+        if (p.X == 0 && p.Y == 0) {
+            return G1Point(0, 0);
+        } else {
+            return G1Point(p.X, PRIME_Q - (p.Y % PRIME_Q));
+        }
+    }
+
+    /*
+     * @return The sum of two points of G1
+     */
+    function plus(
+        G1Point memory p1,
+        G1Point memory p2
+    ) internal view returns (G1Point memory r) {
+
+        uint256[4] memory input;
+        input[0] = p1.X;
+        input[1] = p1.Y;
+        input[2] = p2.X;
+        input[3] = p2.Y;
+        bool success;
+
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 6, input, 0xc0, r, 0x60)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 { invalid() }
+        }
+
+        require(success,"pairing-add-failed");
+    }
+
+
+    /*
+     * Same as plus but accepts raw input instead of struct
+     * @return The sum of two points of G1, one is represented as array
+     */
+    function plus_raw(uint256[4] memory input, G1Point memory r) internal view {
+        bool success;
+
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 6, input, 0xc0, r, 0x60)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 {invalid()}
+        }
+
+        require(success, "pairing-add-failed");
+    }
+
+    /*
+     * @return The product of a point on G1 and a scalar, i.e.
+     *         p == p.scalar_mul(1) and p.plus(p) == p.scalar_mul(2) for all
+     *         points p.
+     */
+    function scalar_mul(G1Point memory p, uint256 s) internal view returns (G1Point memory r) {
+
+        uint256[3] memory input;
+        input[0] = p.X;
+        input[1] = p.Y;
+        input[2] = s;
+        bool success;
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 7, input, 0x80, r, 0x60)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 { invalid() }
+        }
+        require (success,"pairing-mul-failed");
+    }
+
+
+    /*
+     * Same as scalar_mul but accepts raw input instead of struct,
+     * Which avoid extra allocation. provided input can be allocated outside and re-used multiple times
+     */
+    function scalar_mul_raw(uint256[3] memory input, G1Point memory r) internal view {
+        bool success;
+
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 7, input, 0x80, r, 0x60)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 {invalid()}
+        }
+        require(success, "pairing-mul-failed");
+    }
+
+    /* @return The result of computing the pairing check
+     *         e(p1[0], p2[0]) *  .... * e(p1[n], p2[n]) == 1
+     *         For example,
+     *         pairing([P1(), P1().negate()], [P2(), P2()]) should return true.
+     */
+    function pairing(
+        G1Point memory a1,
+        G2Point memory a2,
+        G1Point memory b1,
+        G2Point memory b2,
+        G1Point memory c1,
+        G2Point memory c2,
+        G1Point memory d1,
+        G2Point memory d2
+    ) internal view returns (bool) {
+
+        G1Point[4] memory p1 = [a1, b1, c1, d1];
+        G2Point[4] memory p2 = [a2, b2, c2, d2];
+        uint256 inputSize = 24;
+        uint256[] memory input = new uint256[](inputSize);
+
+        for (uint256 i = 0; i < 4; i++) {
+            uint256 j = i * 6;
+            input[j + 0] = p1[i].X;
+            input[j + 1] = p1[i].Y;
+            input[j + 2] = p2[i].X[0];
+            input[j + 3] = p2[i].X[1];
+            input[j + 4] = p2[i].Y[0];
+            input[j + 5] = p2[i].Y[1];
+        }
+
+        uint256[1] memory out;
+        bool success;
+
+        // solium-disable-next-line security/no-inline-assembly
+        assembly {
+            success := staticcall(sub(gas(), 2000), 8, add(input, 0x20), mul(inputSize, 0x20), out, 0x20)
+            // Use "invalid" to make gas estimation work
+            switch success case 0 { invalid() }
+        }
+
+        require(success,"pairing-opcode-failed");
+
+        return out[0] != 0;
+    }
+}
+
+contract Verifier {
+
+    using Pairing for *;
+
+    uint256 constant SNARK_SCALAR_FIELD = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
+    uint256 constant PRIME_Q = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+
+    struct VerifyingKey {
+        Pairing.G1Point alfa1;
+        Pairing.G2Point beta2;
+        Pairing.G2Point gamma2;
+        Pairing.G2Point delta2;
+        // []G1Point IC (K in gnark) appears directly in verifyProof
+    }
+
+    struct Proof {
+        Pairing.G1Point A;
+        Pairing.G2Point B;
+        Pairing.G1Point C;
+    }
+
+    function verifyingKey() internal pure returns (VerifyingKey memory vk) {
+        vk.alfa1 = Pairing.G1Point(uint256(3390423551084073060221088659216993223487756837637444155662287992635023421359), uint256(2567202639234377532832287293077527240118404110871641804572951559097250297911));
+        vk.beta2 = Pairing.G2Point([uint256(16714355540764232382749749734055536703969225510872641925074257620788875449131), uint256(7937247072060289199992959246679596243366140857248725721894399916894717435762)], [uint256(5099565083030561768574143819689209120419627765722654156883856805225008293860), uint256(16662305731097506437537707257683378426037211001026017512307113853195931314991)]);
+        vk.gamma2 = Pairing.G2Point([uint256(21562673813743066435399410478312673013697848232417954791825593239718297907656), uint256(9788251511650166331988370336768907026212630108680272353986376458022095370219)], [uint256(6121132531051702836893720329166725345940390917258356998953508932534891343517), uint256(7079220999998890750503648237851257479668582143336883049901489358286691624093)]);
+        vk.delta2 = Pairing.G2Point([uint256(4237601330522564245571363577788942009514469117062087627738624372646705305531), uint256(2412079089609869511652527131834579488889464763866352584713936965626292851750)], [uint256(5766384684883971991432376800207434793212940401555373162691311908817851905781), uint256(7760181507494338212842391000595266957200128217467080934541431803965387072595)]);
+    }
+
+
+    // accumulate scalarMul(mul_input) into q
+    // that is computes sets q = (mul_input[0:2] * mul_input[3]) + q
+    function accumulate(
+        uint256[3] memory mul_input,
+        Pairing.G1Point memory p,
+        uint256[4] memory buffer,
+        Pairing.G1Point memory q
+    ) internal view {
+        // computes p = mul_input[0:2] * mul_input[3]
+        Pairing.scalar_mul_raw(mul_input, p);
+
+        // point addition inputs
+        buffer[0] = q.X;
+        buffer[1] = q.Y;
+        buffer[2] = p.X;
+        buffer[3] = p.Y;
+
+        // q = p + q
+        Pairing.plus_raw(buffer, q);
+    }
+
+    /*
+     * @returns Whether the proof is valid given the hardcoded verifying key
+     *          above and the public inputs
+     */
+    function verifyProof(
+        uint256[2] memory a,
+        uint256[2][2] memory b,
+        uint256[2] memory c,
+        uint256[10] calldata input
+    ) public view returns (bool r) {
+
+        Proof memory proof;
+        proof.A = Pairing.G1Point(a[0], a[1]);
+        proof.B = Pairing.G2Point([b[0][0], b[0][1]], [b[1][0], b[1][1]]);
+        proof.C = Pairing.G1Point(c[0], c[1]);
+
+        // Make sure that proof.A, B, and C are each less than the prime q
+        require(proof.A.X < PRIME_Q, "verifier-aX-gte-prime-q");
+        require(proof.A.Y < PRIME_Q, "verifier-aY-gte-prime-q");
+
+        require(proof.B.X[0] < PRIME_Q, "verifier-bX0-gte-prime-q");
+        require(proof.B.Y[0] < PRIME_Q, "verifier-bY0-gte-prime-q");
+
+        require(proof.B.X[1] < PRIME_Q, "verifier-bX1-gte-prime-q");
+        require(proof.B.Y[1] < PRIME_Q, "verifier-bY1-gte-prime-q");
+
+        require(proof.C.X < PRIME_Q, "verifier-cX-gte-prime-q");
+        require(proof.C.Y < PRIME_Q, "verifier-cY-gte-prime-q");
+
+        // Make sure that every input is less than the snark scalar field
+        for (uint256 i = 0; i < input.length; i++) {
+            require(input[i] < SNARK_SCALAR_FIELD,"verifier-gte-snark-scalar-field");
+        }
+
+        VerifyingKey memory vk = verifyingKey();
+
+        // Compute the linear combination vk_x
+        Pairing.G1Point memory vk_x = Pairing.G1Point(0, 0);
+
+        // Buffer reused for addition p1 + p2 to avoid memory allocations
+        // [0:2] -> p1.X, p1.Y ; [2:4] -> p2.X, p2.Y
+        uint256[4] memory add_input;
+
+        // Buffer reused for multiplication p1 * s
+        // [0:2] -> p1.X, p1.Y ; [3] -> s
+        uint256[3] memory mul_input;
+
+        // temporary point to avoid extra allocations in accumulate
+        Pairing.G1Point memory q = Pairing.G1Point(0, 0);
+
+        vk_x.X = uint256(16527135364181887281469216131363316255840520607940837289446596623880394486407); // vk.K[0].X
+        vk_x.Y = uint256(13115660080511181429465101920976573374416980453408745427652101562260236829124); // vk.K[0].Y
+        mul_input[0] = uint256(12022381476806802340355416448997825168777781905442115023439970942318340212547); // vk.K[1].X
+        mul_input[1] = uint256(5062784974938909953647395231994422031398728134302468580917621404552130776835); // vk.K[1].Y
+        mul_input[2] = input[0];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[1] * input[0]
+        mul_input[0] = uint256(341703925644687974376828535072202239991694582576102888169160152699433740718); // vk.K[2].X
+        mul_input[1] = uint256(5430644574982071046785373344370656151000598786261470957733434636106178982348); // vk.K[2].Y
+        mul_input[2] = input[1];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[2] * input[1]
+        mul_input[0] = uint256(3267360907283866983694466320885176827371319090005934360519200707023612217665); // vk.K[3].X
+        mul_input[1] = uint256(6805411537148565998627882457505053262514773093114885620059115833634351130343); // vk.K[3].Y
+        mul_input[2] = input[2];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[3] * input[2]
+        mul_input[0] = uint256(8397955478376877290070447820750740275262948054971086209086481511032172738315); // vk.K[4].X
+        mul_input[1] = uint256(4814511108346376391523515767931057706363771403034551885370386602397933788967); // vk.K[4].Y
+        mul_input[2] = input[3];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[4] * input[3]
+        mul_input[0] = uint256(16954246839251260689142149308919071688317513444433626794527363594269755420154); // vk.K[5].X
+        mul_input[1] = uint256(1039967553785477065145821015005227019319357042115796701793048980485678594312); // vk.K[5].Y
+        mul_input[2] = input[4];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[5] * input[4]
+        mul_input[0] = uint256(7391785703090882591711055714834626341152098305257870853603077680805712355916); // vk.K[6].X
+        mul_input[1] = uint256(13384418933887059873125356370909027617640378746922866181544603108297852342974); // vk.K[6].Y
+        mul_input[2] = input[5];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[6] * input[5]
+        mul_input[0] = uint256(19754850526510062935969978072619856859017890319656298533235912824860442944267); // vk.K[7].X
+        mul_input[1] = uint256(6833165055011880420117809613897926661474487756705940207005957409120609301202); // vk.K[7].Y
+        mul_input[2] = input[6];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[7] * input[6]
+        mul_input[0] = uint256(13909715825226565271414006553471352309482170504581855602141818396401885724277); // vk.K[8].X
+        mul_input[1] = uint256(2446631773235862687151230334654160365744866722891070396724902958166457911118); // vk.K[8].Y
+        mul_input[2] = input[7];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[8] * input[7]
+        mul_input[0] = uint256(5687965324157184578284237598665285913815454717280685327196196518362953595693); // vk.K[9].X
+        mul_input[1] = uint256(9510117726297051760154062592156921945651075125029389390562216277035036366976); // vk.K[9].Y
+        mul_input[2] = input[8];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[9] * input[8]
+        mul_input[0] = uint256(19241053764829201483438560064723254431077302352083084537973479070632016832773); // vk.K[10].X
+        mul_input[1] = uint256(17599110672747754994809147087945115221134572418957123626090123893463289403426); // vk.K[10].Y
+        mul_input[2] = input[9];
+        accumulate(mul_input, q, add_input, vk_x); // vk_x += vk.K[10] * input[9]
+
+        return Pairing.pairing(
+            Pairing.negate(proof.A),
+            proof.B,
+            vk.alfa1,
+            vk.beta2,
+            vk_x,
+            vk.gamma2,
+            proof.C,
+            vk.delta2
+        );
+    }
+}
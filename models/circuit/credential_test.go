@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadIssuerKeyRevoked(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "issuer.pem")
+	if err = WriteIssuerKey(key, fileName); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadIssuerKey(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(revoked []string) { revokedIssuers = revoked }(revokedIssuers)
+	revokedIssuers = append(revokedIssuers, hex.EncodeToString(key.Public().Bytes()))
+	if _, err = ReadIssuerKey(fileName); !errors.Is(err, ErrIssuerKeyRevoked) {
+		t.Fatalf("revoked issuer key read with %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	frbls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	frbls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	frbn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	frbw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/backend/witness"
+	"math/big"
+)
+
+var ErrCurveNotSupported = errors.New("curve not supported")
+
+// curves a circuit can be deployed on, BN254 is the only one with a solidity verifier
+// and BW6-761 carries the aggregators of BLS12-377 proofs
+var curves = []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377, ecc.BW6_761}
+
+// mimcCurves are the curves MiMC circuits over the customer commitment can be
+// configured on, their scalar fields fit the 32 bytes blocks the circuits hash
+var mimcCurves = []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BLS12_377}
+
+// ParseCurve returns the curve of a name, an empty name is BN254 which every proof
+// was made on before circuits declared their curve
+func ParseCurve(name string) (ecc.ID, error) {
+	if name == "" {
+		return ecc.BN254, nil
+	}
+	for _, id := range curves {
+		if id.String() == name {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("%w : %s", ErrCurveNotSupported, name)
+}
+
+func checkCurve(curve ecc.ID) error {
+	for _, id := range curves {
+		if id == curve {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w : %s", ErrCurveNotSupported, curve)
+}
+
+// mimcHash returns the decimal MiMC hash of data over the curve scalar field, the
+// native counterpart of the circuit MiMC
+func mimcHash(curve ecc.ID, data []byte) (string, error) {
+	sum, err := mimcSum(curve, data)
+	if err != nil {
+		return "", err
+	}
+	return sum.String(), nil
+}
+
+// mimcSum returns the MiMC hash of data over the curve scalar field
+func mimcSum(curve ecc.ID, data []byte) (*big.Int, error) {
+	var f hash.Hash
+	switch curve {
+	case ecc.BN254:
+		f = hash.MIMC_BN254
+	case ecc.BLS12_381:
+		f = hash.MIMC_BLS12_381
+	case ecc.BLS12_377:
+		f = hash.MIMC_BLS12_377
+	case ecc.BW6_761:
+		f = hash.MIMC_BW6_761
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrCurveNotSupported, curve)
+	}
+	h := f.New()
+	if _, err := h.Write(data); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// fieldBytes maps arbitrary data to a scalar field element of the curve, returned as
+// the 32 bytes big-endian block expected by MiMC
+func fieldBytes(curve ecc.ID, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	e := new(big.Int).SetBytes(digest[:])
+	e.Mod(e, curve.ScalarField())
+	return e.FillBytes(make([]byte, 32))
+}
+
+// publicValues returns the public witness as integers, whatever its curve
+func publicValues(publicWitness witness.Witness) ([]*big.Int, error) {
+	var values []*big.Int
+	switch vector := publicWitness.Vector().(type) {
+	case frbn254.Vector:
+		for i := range vector {
+			values = append(values, vector[i].BigInt(new(big.Int)))
+		}
+	case frbls12381.Vector:
+		for i := range vector {
+			values = append(values, vector[i].BigInt(new(big.Int)))
+		}
+	case frbls12377.Vector:
+		for i := range vector {
+			values = append(values, vector[i].BigInt(new(big.Int)))
+		}
+	case frbw6761.Vector:
+		for i := range vector {
+			values = append(values, vector[i].BigInt(new(big.Int)))
+		}
+	default:
+		return nil, fmt.Errorf("%w : public witness of type %T", ErrCurveNotSupported, vector)
+	}
+	return values, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"smart-contract-service/models"
+	"testing"
+)
+
+func init() {
+	Register(&Definition{
+		Name:        "hash-configured",
+		Circuit:     func() frontend.Circuit { return &Circuit{} },
+		Assign:      assignHash,
+		CheckPublic: checkHashPublic,
+		Nullifier:   hashNullifier,
+		Artifact:    NewArtifact("hash-configured"),
+		Curves:      mimcCurves,
+	})
+}
+
+// enrolled returns a customer with a commitment enrolled on CommitmentCurve
+func enrolled(t *testing.T) *models.Customer {
+	t.Helper()
+	cData := &models.Customer{Id: "customer", KTP: "3171234567890001", NoRek: "1234567890", MotherName: "Siti"}
+	salt, err := GenerateSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cData.Commitment, err = Commitment(CommitmentCurve, cData, salt); err != nil {
+		t.Fatal(err)
+	}
+	cData.CommitmentSalt = salt
+	return cData
+}
+
+func TestConfigureCurve(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BLS12_381, ecc.BLS12_377} {
+		t.Run(curve.String(), func(t *testing.T) {
+			if err := Configure(map[string]string{"hash-configured": curve.String()}); err != nil {
+				t.Fatal(err)
+			}
+			def, err := Lookup("hash-configured")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if def.Curve != curve {
+				t.Fatalf("circuit on %s, configured on %s", def.Curve, curve)
+			}
+
+			ccs, err := Compile(def.Curve, backend.GROTH16, def.Circuit())
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := Setup(def.Curve, backend.GROTH16, ccs, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := &WitnessInput{Customer: enrolled(t), Curve: def.Curve, Context: "payment-1"}
+			assignment, err := def.Assign(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			full, err := frontend.NewWitness(assignment, def.Curve.ScalarField())
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof, err := keys.Prove(full)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public, err := full.Public()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = keys.Verify(proof, public); err != nil {
+				t.Fatal(err)
+			}
+			if err = def.CheckPublic(public, in); err != nil {
+				t.Fatal(err)
+			}
+
+			// the enrolled commitment no longer opens to the attributes
+			in.Customer.MotherName = "Aminah"
+			if err = def.CheckPublic(public, in); !errors.Is(err, ErrCommitmentMismatch) {
+				t.Fatalf("changed attributes checked with %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigureCurveRefused(t *testing.T) {
+	for name, curve := range map[string]string{
+		EddsaAlgorithm:    ecc.BLS12_381.String(),
+		"hash-configured": ecc.BW6_761.String(),
+		"unknown":         ecc.BN254.String(),
+	} {
+		err := Configure(map[string]string{name: curve})
+		if !errors.Is(err, ErrCurveNotSupported) && !errors.Is(err, ErrAlgorithmNotFound) {
+			t.Fatalf("%s configured on %s with %v", name, curve, err)
+		}
+	}
+}
@@ -1,13 +1,52 @@
 package models
 
 import (
+	"crypto/rand"
+	"errors"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	eddsabn254 "github.com/consensys/gnark-crypto/ecc/bn254/twistededwards/eddsa"
 	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	eddsa2 "github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/algebra/twistededwards"
 	mimc2 "github.com/consensys/gnark/std/hash/mimc"
 	"github.com/consensys/gnark/std/signature/eddsa"
+	"math/big"
+	"smart-contract-service/models"
 )
 
+const EddsaAlgorithm = "eddsa"
+
+var (
+	ErrSigningKeyMissing = errors.New("no signing key registered for customer")
+	ErrPublicKeyMissing  = errors.New("no public key registered for customer")
+	ErrPublicKeyMismatch = errors.New("public key does not match the registered key")
+)
+
+func init() {
+	Register(&Definition{
+		Name:        EddsaAlgorithm,
+		Circuit:     func() frontend.Circuit { return &EddsaCircuit{} },
+		Assign:      assignEddsa,
+		CheckPublic: checkEddsaPublic,
+		Artifact:    NewArtifact("eddsa"),
+		// the signatures are on the twisted Edwards curve embedded in BN254
+		Curve: ecc.BN254,
+		Inputs: []PublicInput{
+			{Name: "PublicKey_A_X", Description: "x of the customer EdDSA public key"},
+			{Name: "PublicKey_A_Y", Description: "y of the customer EdDSA public key"},
+			{Name: "Signature_R_X", Description: "x of the signature point R"},
+			{Name: "Signature_R_Y", Description: "y of the signature point R"},
+			{Name: "Signature_S", Description: "signature scalar S"},
+			{Name: "Message", Description: "commitment enrolled on the customer, signed with their key"},
+		},
+	})
+}
+
 type EddsaCircuit struct {
 	PublicKey eddsa.PublicKey   `gnark:",public"`
 	Signature eddsa.Signature   `gnark:",public"`
@@ -28,3 +67,116 @@ func (circuit *EddsaCircuit) Define(api frontend.API) error {
 	// verify the signature in the cs
 	return eddsa.Verify(curve, circuit.Signature, circuit.Message, circuit.PublicKey, &mimc)
 }
+
+func assignEddsa(in *WitnessInput) (frontend.Circuit, error) {
+	cData := in.Customer
+	if in.SigningKey == nil {
+		return nil, ErrSigningKeyMissing
+	}
+
+	// instantiate hash function
+	f := bn254.NewMiMC()
+
+	// use the key pair registered for the customer
+	privateKey := in.SigningKey
+	publicKey := privateKey.Public()
+
+	// the key signs the commitment enrolled for the customer, checkEddsaPublic reads it back
+	message, err := eddsaMessage(cData)
+	if err != nil {
+		return nil, err
+	}
+	b := message.FillBytes(make([]byte, 32))
+
+	// sign the message
+	signature, err := privateKey.Sign(b, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// verifies signature
+	isValid, err := publicKey.Verify(signature, b, f)
+	if !isValid {
+		return nil, errors.New("not valid")
+	}
+	// declare the witness
+	assignment := &EddsaCircuit{}
+
+	// assign message value
+	assignment.Message = b
+
+	// public key bytes
+	_publicKey := publicKey.Bytes()
+
+	// assign public key values
+	assignment.PublicKey.Assign(tedwards.BN254, _publicKey[:32])
+
+	// assign signature values
+	assignment.Signature.Assign(tedwards.BN254, signature)
+	return assignment, nil
+}
+
+// eddsaMessage returns the message the customer key signs, the commitment enrolled on BN254
+func eddsaMessage(cData *models.Customer) (*big.Int, error) {
+	commitment, err := enrolledCommitment(ecc.BN254, cData)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := new(big.Int).SetString(commitment, 10)
+	if !ok {
+		return nil, errors.New("enrolled commitment is not a decimal field element")
+	}
+	return message, nil
+}
+
+// checkEddsaPublic asserts the proof was made with the public key registered for the customer
+// over the commitment enrolled for them, the public witness starts with the coordinates of
+// EddsaCircuit.PublicKey and ends with the message
+func checkEddsaPublic(publicWitness witness.Witness, in *WitnessInput) error {
+	if in.PublicKey == nil {
+		return ErrPublicKeyMissing
+	}
+	registered, ok := in.PublicKey.(*eddsabn254.PublicKey)
+	if !ok {
+		return errors.New("registered key is not a BN254 EdDSA key")
+	}
+
+	public, ok := publicWitness.Vector().(fr.Vector)
+	if !ok || len(public) != 6 {
+		return errors.New("public witness is not a BN254 EdDSA witness")
+	}
+	if !public[0].Equal(&registered.A.X) || !public[1].Equal(&registered.A.Y) {
+		return ErrPublicKeyMismatch
+	}
+	message, err := eddsaMessage(in.Customer)
+	if err != nil {
+		return err
+	}
+	if public[5].BigInt(new(big.Int)).Cmp(message) != 0 {
+		return ErrCommitmentMismatch
+	}
+	return nil
+}
+
+// GenerateSigningKey creates a new EdDSA key pair on the BN254 twisted Edwards curve
+func GenerateSigningKey() (signature.Signer, error) {
+	return eddsa2.New(tedwards.BN254, rand.Reader)
+}
+
+// ParseSigningKey decodes a private key serialized with Signer.Bytes
+func ParseSigningKey(data []byte) (signature.Signer, error) {
+	privateKey := &eddsabn254.PrivateKey{}
+	if _, err := privateKey.SetBytes(data); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// ParsePublicKey decodes a compressed public key serialized with PublicKey.Bytes
+func ParsePublicKey(data []byte) (signature.PublicKey, error) {
+	publicKey := &eddsabn254.PublicKey{}
+	if _, err := publicKey.SetBytes(data); err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
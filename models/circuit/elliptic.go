@@ -1,7 +1,25 @@
 package models
 
-import "github.com/consensys/gnark/frontend"
+import (
+	"github.com/consensys/gnark/frontend"
+)
 
+const EllipticAlgorithm = "elliptic"
+
+func init() {
+	Register(&Definition{
+		Name:       EllipticAlgorithm,
+		Circuit:    func() frontend.Circuit { return &EllipticCurve{} },
+		Assign:     assignElliptic,
+		Inputs:     []PublicInput{{Name: "Y", Type: InputUint64, Description: "x^3+x+5 for the customer name length x"}},
+		Artifact:   NewArtifact("elliptic"),
+		Deprecated: CredentialAlgorithm,
+	})
+}
+
+// EllipticCurve proves knowledge of x with x^3+x+5 = y, x being the customer name length.
+// It proves nothing about the customer and is kept for existing callers only, use
+// CredentialCircuit instead.
 type EllipticCurve struct {
 	X frontend.Variable
 	Y frontend.Variable `gnark:",public"`
@@ -18,3 +36,12 @@ func (circuit *EllipticCurve) Define(api frontend.API) error {
 	api.AssertIsEqual(circuit.Y, res)
 	return nil
 }
+
+func assignElliptic(in *WitnessInput) (frontend.Circuit, error) {
+	cData := in.Customer
+	assignment := &EllipticCurve{}
+	x := len(cData.Name)
+	assignment.X = frontend.Variable(x)
+	assignment.Y = frontend.Variable(x*x*x + x + 5)
+	return assignment, nil
+}
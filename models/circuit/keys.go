@@ -0,0 +1,259 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	kzgbls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/kzg"
+	kzgbls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/kzg"
+	kzgbn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	kzgbw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	r1cs2 "github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"io"
+	"path"
+	"smart-contract-service/internal"
+)
+
+var ErrBackendNotSupported = errors.New("proving backend not supported")
+
+// ProvingKey is implemented by the groth16 and plonk proving keys
+type ProvingKey interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// VerifyingKey is implemented by the groth16 and plonk verifying keys
+type VerifyingKey interface {
+	io.WriterTo
+	io.ReaderFrom
+	NbPublicWitness() int
+	ExportSolidity(w io.Writer) error
+}
+
+// Proof is implemented by the groth16 and plonk proofs
+type Proof interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// Keys holds the deserialized artifacts of a registered circuit for one backend
+type Keys struct {
+	Curve   ecc.ID
+	Backend backend.ID
+	Cs      constraint.ConstraintSystem
+	Pk      ProvingKey
+	Vk      VerifyingKey
+}
+
+// ParseBackend returns the backend of a name, an empty name is groth16 which
+// every proof was made with before plonk was supported
+func ParseBackend(name string) (backend.ID, error) {
+	switch name {
+	case "", backend.GROTH16.String():
+		return backend.GROTH16, nil
+	case backend.PLONK.String():
+		return backend.PLONK, nil
+	default:
+		return backend.UNKNOWN, fmt.Errorf("%w : %s", ErrBackendNotSupported, name)
+	}
+}
+
+// NewKeys returns empty keys of the curve and backend, ready to be deserialized
+func NewKeys(curve ecc.ID, b backend.ID) (*Keys, error) {
+	if err := checkCurve(curve); err != nil {
+		return nil, err
+	}
+	switch b {
+	case backend.GROTH16:
+		return &Keys{
+			Curve:   curve,
+			Backend: b,
+			Cs:      groth16.NewCS(curve),
+			Pk:      groth16.NewProvingKey(curve),
+			Vk:      groth16.NewVerifyingKey(curve),
+		}, nil
+	case backend.PLONK:
+		return &Keys{
+			Curve:   curve,
+			Backend: b,
+			Cs:      plonk.NewCS(curve),
+			Pk:      plonk.NewProvingKey(curve),
+			Vk:      plonk.NewVerifyingKey(curve),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrBackendNotSupported, b)
+	}
+}
+
+// NewProof returns an empty proof of the curve and backend, ready to be deserialized
+func NewProof(curve ecc.ID, b backend.ID) (Proof, error) {
+	if err := checkCurve(curve); err != nil {
+		return nil, err
+	}
+	switch b {
+	case backend.GROTH16:
+		return groth16.NewProof(curve), nil
+	case backend.PLONK:
+		return plonk.NewProof(curve), nil
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrBackendNotSupported, b)
+	}
+}
+
+// Compile builds the constraint system of a circuit over the curve scalar field for
+// the backend, R1CS for groth16 and SparseR1CS for plonk
+func Compile(curve ecc.ID, b backend.ID, circuit frontend.Circuit) (constraint.ConstraintSystem, error) {
+	if err := checkCurve(curve); err != nil {
+		return nil, err
+	}
+	switch b {
+	case backend.GROTH16:
+		return frontend.Compile(curve.ScalarField(), r1cs2.NewBuilder, circuit)
+	case backend.PLONK:
+		return frontend.Compile(curve.ScalarField(), scs.NewBuilder, circuit)
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrBackendNotSupported, b)
+	}
+}
+
+// Setup runs the setup of the backend, plonk derives its keys from the universal
+// srs while groth16 samples fresh toxic waste for the circuit
+func Setup(curve ecc.ID, b backend.ID, ccs constraint.ConstraintSystem, srs kzg.SRS) (*Keys, error) {
+	keys := &Keys{Curve: curve, Backend: b, Cs: ccs}
+	var err error
+	switch b {
+	case backend.GROTH16:
+		keys.Pk, keys.Vk, err = groth16.Setup(ccs)
+	case backend.PLONK:
+		if srs == nil {
+			return nil, errors.New("plonk setup needs a kzg srs")
+		}
+		keys.Pk, keys.Vk, err = plonk.Setup(ccs, srs)
+	default:
+		err = fmt.Errorf("%w : %s", ErrBackendNotSupported, b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// InitKZG attaches the srs to deserialized plonk keys, which do not store it
+func (k *Keys) InitKZG(srs kzg.SRS) error {
+	if k.Backend != backend.PLONK {
+		return nil
+	}
+	if srs == nil {
+		return errors.New("plonk keys need a kzg srs")
+	}
+	if err := k.Pk.(plonk.ProvingKey).InitKZG(srs); err != nil {
+		return err
+	}
+	return k.Vk.(plonk.VerifyingKey).InitKZG(srs)
+}
+
+func (k *Keys) Prove(fullWitness witness.Witness) (Proof, error) {
+	switch k.Backend {
+	case backend.GROTH16:
+		return groth16.Prove(k.Cs, k.Pk.(groth16.ProvingKey), fullWitness)
+	case backend.PLONK:
+		return plonk.Prove(k.Cs, k.Pk.(plonk.ProvingKey), fullWitness)
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrBackendNotSupported, k.Backend)
+	}
+}
+
+func (k *Keys) Verify(proof Proof, publicWitness witness.Witness) error {
+	switch k.Backend {
+	case backend.GROTH16:
+		p, ok := proof.(groth16.Proof)
+		if !ok {
+			return errors.New("not a groth16 proof")
+		}
+		return groth16.Verify(p, k.Vk.(groth16.VerifyingKey), publicWitness)
+	case backend.PLONK:
+		p, ok := proof.(plonk.Proof)
+		if !ok {
+			return errors.New("not a plonk proof")
+		}
+		return plonk.Verify(p, k.Vk.(plonk.VerifyingKey), publicWitness)
+	default:
+		return fmt.Errorf("%w : %s", ErrBackendNotSupported, k.Backend)
+	}
+}
+
+// SRSSize returns the number of srs points plonk needs to set up the constraint system
+func SRSSize(ccs constraint.ConstraintSystem) uint64 {
+	return ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()+ccs.GetNbPublicVariables())) + 3
+}
+
+// NewSRS samples a kzg srs of the given size on the curve. Whoever runs it knows the
+// secret, production deployments should import the srs of a public ceremony instead.
+func NewSRS(curve ecc.ID, size uint64) (kzg.SRS, error) {
+	alpha, err := rand.Int(rand.Reader, curve.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	switch curve {
+	case ecc.BN254:
+		return kzgbn254.NewSRS(size, alpha)
+	case ecc.BLS12_381:
+		return kzgbls12381.NewSRS(size, alpha)
+	case ecc.BLS12_377:
+		return kzgbls12377.NewSRS(size, alpha)
+	case ecc.BW6_761:
+		return kzgbw6761.NewSRS(size, alpha)
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrCurveNotSupported, curve)
+	}
+}
+
+// NewEmptySRS returns an empty srs of the curve ready to be deserialized
+func NewEmptySRS(curve ecc.ID) (kzg.SRS, error) {
+	switch curve {
+	case ecc.BN254:
+		return &kzgbn254.SRS{}, nil
+	case ecc.BLS12_381:
+		return &kzgbls12381.SRS{}, nil
+	case ecc.BLS12_377:
+		return &kzgbls12377.SRS{}, nil
+	case ecc.BW6_761:
+		return &kzgbw6761.SRS{}, nil
+	default:
+		return nil, fmt.Errorf("%w : %s", ErrCurveNotSupported, curve)
+	}
+}
+
+// SRSLen returns the number of G1 points of a srs
+func SRSLen(srs kzg.SRS) int {
+	switch s := srs.(type) {
+	case *kzgbn254.SRS:
+		return len(s.G1)
+	case *kzgbls12381.SRS:
+		return len(s.G1)
+	case *kzgbls12377.SRS:
+		return len(s.G1)
+	case *kzgbw6761.SRS:
+		return len(s.G1)
+	default:
+		return 0
+	}
+}
+
+// SRSPath returns the location of the universal srs shared by the plonk circuits of
+// the curve, the BN254 one keeps the location it had before other curves
+func SRSPath(curve ecc.ID) string {
+	if curve == ecc.BN254 {
+		return internal.SrsPath
+	}
+	return path.Join(internal.CircuitDir, "kzg."+curve.String()+".srs")
+}
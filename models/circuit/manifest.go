@@ -0,0 +1,144 @@
+package models
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark/backend"
+	"os"
+	"smart-contract-service/internal"
+	"time"
+)
+
+// ManifestEntry records how the artifacts of a circuit were produced
+type ManifestEntry struct {
+	Circuit      string `json:"circuit"`
+	Backend      string `json:"backend,omitempty"` // groth16 when empty
+	Constraints  int    `json:"constraints"`
+	Curve        string `json:"curve"`
+	R1csSha256   string `json:"r1csSha256"`
+	PkSha256     string `json:"pkSha256"`
+	VkSha256     string `json:"vkSha256"`
+	SrsSha256    string `json:"srsSha256,omitempty"` // plonk only, the universal srs the keys derive from
+	Ceremony     string `json:"ceremony,omitempty"`  // groth16 keys of a ceremony, sha256 of the last contribution
+	GnarkVersion string `json:"gnarkVersion"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// Manifest lists the artifacts written by -init, signed with the service RSA key
+type Manifest struct {
+	Circuits  []ManifestEntry `json:"circuits"`
+	Signature string          `json:"signature"`
+}
+
+// NewManifestEntry checksums the artifacts of a freshly set up circuit
+func NewManifestEntry(def *Definition, b backend.ID, nbConstraints int) (entry ManifestEntry, err error) {
+	entry = ManifestEntry{
+		Circuit:      def.Name,
+		Backend:      b.String(),
+		Constraints:  nbConstraints,
+		Curve:        def.Curve.String(),
+		GnarkVersion: gnark.Version.String(),
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+	artifact := def.Artifact.For(b)
+	if entry.R1csSha256, err = internal.FileSha256(artifact.R1cs); err != nil {
+		return
+	}
+	if entry.PkSha256, err = internal.FileSha256(artifact.Pk); err != nil {
+		return
+	}
+	if entry.VkSha256, err = internal.FileSha256(artifact.Vk); err != nil {
+		return
+	}
+	if b == backend.PLONK {
+		entry.SrsSha256, err = internal.FileSha256(SRSPath(def.Curve))
+	}
+	return
+}
+
+// payload is the signed content of the manifest
+func (m *Manifest) payload() ([]byte, error) {
+	return json.Marshal(m.Circuits)
+}
+
+func (m *Manifest) Sign(key *rsa.PrivateKey) (err error) {
+	payload, err := m.payload()
+	if err != nil {
+		return err
+	}
+	m.Signature, err = internal.SignRSA256(payload, key)
+	return err
+}
+
+func (m *Manifest) Verify(key *rsa.PublicKey) error {
+	payload, err := m.payload()
+	if err != nil {
+		return err
+	}
+	if err = internal.VerifyRSA256(payload, m.Signature, key); err != nil {
+		return fmt.Errorf("manifest signature not valid : %w", err)
+	}
+	return nil
+}
+
+func (m *Manifest) Entry(name string, b backend.ID) (*ManifestEntry, error) {
+	for i := range m.Circuits {
+		entryBackend, err := ParseBackend(m.Circuits[i].Backend)
+		if err != nil {
+			continue
+		}
+		if m.Circuits[i].Circuit == name && entryBackend == b {
+			return &m.Circuits[i], nil
+		}
+	}
+	return nil, fmt.Errorf("circuit %s (%s) not in manifest", name, b)
+}
+
+// Check verifies the artifacts on disk are the ones recorded for the circuit
+func (e *ManifestEntry) Check(def *Definition, b backend.ID) error {
+	if e.Curve != def.Curve.String() {
+		return fmt.Errorf("manifest curve %s, expected %s", e.Curve, def.Curve)
+	}
+	artifact := def.Artifact.For(b)
+	sums := map[string]string{
+		artifact.R1cs: e.R1csSha256,
+		artifact.Pk:   e.PkSha256,
+		artifact.Vk:   e.VkSha256,
+	}
+	if b == backend.PLONK {
+		sums[SRSPath(def.Curve)] = e.SrsSha256
+	}
+	for fileName, expected := range sums {
+		sum, err := internal.FileSha256(fileName)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			return errors.New("checksum mismatch for " + fileName)
+		}
+	}
+	return nil
+}
+
+func WriteManifest(m *Manifest, fileName string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+func ReadManifest(fileName string) (*Manifest, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	mimc2 "github.com/consensys/gnark/std/hash/mimc"
+	"math/big"
+)
+
+const (
+	MembershipAlgorithm = "membership"
+	// MembershipDepth bounds the tree to 2^20 customers
+	MembershipDepth = 20
+	// membershipDomain separates membership nullifiers from the hash circuit ones, so the
+	// two proofs of a customer for a context cannot be linked
+	membershipDomain = 1
+)
+
+var (
+	ErrNotMember   = errors.New("customer commitment is not in the membership tree")
+	ErrRootUnknown = errors.New("membership root was not published by the service")
+)
+
+func init() {
+	Register(&Definition{
+		Name:        MembershipAlgorithm,
+		Circuit:     func() frontend.Circuit { return &MembershipCircuit{} },
+		Assign:      assignMembership,
+		CheckPublic: checkMembershipPublic,
+		Nullifier:   membershipNullifier,
+		Artifact:    NewArtifact("membership"),
+		Anonymous:   true,
+		Inputs: []PublicInput{
+			{Name: "Root", Description: "membership tree root published by the service"},
+			hashInputs[1],
+			{Name: "Nullifier", Description: "spent once per context, unlinkable to the customer and the hash circuit nullifier"},
+		},
+	})
+}
+
+// MembershipCircuit proves the customer commitment is a leaf of the published tree of
+// enrolled customers without revealing which one, only the root is public
+type MembershipCircuit struct {
+	KTP        frontend.Variable
+	Account    frontend.Variable
+	MotherName frontend.Variable
+	Salt       frontend.Variable
+	// Path holds the siblings from the leaf up, Index the bits of the leaf position
+	Path      [MembershipDepth]frontend.Variable
+	Index     [MembershipDepth]frontend.Variable
+	Root      frontend.Variable `gnark:",public"`
+	Context   frontend.Variable `gnark:",public"`
+	Nullifier frontend.Variable `gnark:",public"`
+}
+
+func (circuit *MembershipCircuit) Define(api frontend.API) error {
+	mimc, err := mimc2.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	mimc.Write(circuit.KTP, circuit.Account, circuit.MotherName, circuit.Salt)
+	node := mimc.Sum()
+
+	for i := range circuit.Path {
+		// a set bit puts the node on the right of its sibling
+		api.AssertIsBoolean(circuit.Index[i])
+		left := api.Select(circuit.Index[i], circuit.Path[i], node)
+		right := api.Select(circuit.Index[i], node, circuit.Path[i])
+		mimc.Reset()
+		mimc.Write(left, right)
+		node = mimc.Sum()
+	}
+	api.AssertIsEqual(circuit.Root, node)
+
+	mimc.Reset()
+	mimc.Write(circuit.Salt, circuit.Context, membershipDomain)
+	api.AssertIsEqual(circuit.Nullifier, mimc.Sum())
+	return nil
+}
+
+func assignMembership(in *WitnessInput) (frontend.Circuit, error) {
+	// without a context the proof is made for a random nonce, it is then single use
+	context := in.Context
+	if context == "" {
+		var err error
+		if context, err = GenerateSalt(); err != nil {
+			return nil, err
+		}
+	}
+	contextBin := fieldBytes(in.Curve, []byte(context))
+	commitment, err := assignCommitment(in, contextBin)
+	if err != nil {
+		return nil, err
+	}
+	path := in.Membership
+	if path == nil || len(path.Siblings) != MembershipDepth || path.Leaf.String() != in.Customer.Commitment {
+		return nil, ErrNotMember
+	}
+
+	preimage, err := commitmentPreimage(in.Curve, in.Customer, in.Customer.CommitmentSalt)
+	if err != nil {
+		return nil, err
+	}
+	var nullifierData []byte
+	nullifierData = append(nullifierData, preimage[3]...)
+	nullifierData = append(nullifierData, contextBin...)
+	nullifierData = append(nullifierData, big.NewInt(membershipDomain).FillBytes(make([]byte, 32))...)
+	nullifier, err := mimcSum(in.Curve, nullifierData)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := &MembershipCircuit{
+		KTP:        commitment.KTP,
+		Account:    commitment.Account,
+		MotherName: commitment.MotherName,
+		Salt:       commitment.Salt,
+		Root:       path.Root,
+		Context:    contextBin,
+		Nullifier:  nullifier,
+	}
+	for i := range assignment.Path {
+		assignment.Path[i] = path.Siblings[i]
+		assignment.Index[i] = (path.Index >> i) & 1
+	}
+	return assignment, nil
+}
+
+// checkMembershipPublic asserts the proof was made against a root published by the
+// service, the customer itself is not checked as the proof does not reveal it
+func checkMembershipPublic(publicWitness witness.Witness, in *WitnessInput) error {
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return err
+	}
+	if len(public) != 3 {
+		return errors.New("public witness is not a membership witness")
+	}
+	if in.KnownRoot == nil || !in.KnownRoot(public[0]) {
+		return ErrRootUnknown
+	}
+	return nil
+}
+
+// membershipNullifier returns the nullifier of a verified membership witness, after the
+// root and the context
+func membershipNullifier(publicWitness witness.Witness) (string, error) {
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return "", err
+	}
+	if len(public) != 3 {
+		return "", errors.New("public witness is not a membership witness")
+	}
+	return public[2].String(), nil
+}
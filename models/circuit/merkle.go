@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"math/big"
+)
+
+var ErrMerkleTreeFull = errors.New("merkle tree is full")
+
+// MerkleTree is a fixed depth MiMC merkle tree, the native counterpart of the membership
+// circuit. Leaves are appended from the left, the leaves not set yet are zero.
+type MerkleTree struct {
+	curve ecc.ID
+	depth int
+	// nodes[0] are the leaves and nodes[depth] the root, a level only holds the nodes
+	// over the leaves set so far
+	nodes [][]*big.Int
+	// zeros[l] is the node of level l over zero leaves only
+	zeros []*big.Int
+}
+
+// MerklePath proves a leaf is in the tree of the root, siblings go from the leaf up
+type MerklePath struct {
+	Root     *big.Int
+	Leaf     *big.Int
+	Index    int
+	Siblings []*big.Int
+}
+
+// NewMerkleTree returns an empty tree of 2^depth leaves hashed over the curve scalar field
+func NewMerkleTree(curve ecc.ID, depth int) (*MerkleTree, error) {
+	t := &MerkleTree{
+		curve: curve,
+		depth: depth,
+		nodes: make([][]*big.Int, depth+1),
+		zeros: make([]*big.Int, depth+1),
+	}
+	t.zeros[0] = new(big.Int)
+	for l := 0; l < depth; l++ {
+		zero, err := t.hashPair(t.zeros[l], t.zeros[l])
+		if err != nil {
+			return nil, err
+		}
+		t.zeros[l+1] = zero
+	}
+	return t, nil
+}
+
+// Len returns the number of leaves set, zero leaves included
+func (t *MerkleTree) Len() int {
+	return len(t.nodes[0])
+}
+
+// Root returns the root of the tree
+func (t *MerkleTree) Root() *big.Int {
+	return t.node(t.depth, 0)
+}
+
+// Leaf returns the leaf at index, zero when not set
+func (t *MerkleTree) Leaf(index int) *big.Int {
+	return t.node(0, index)
+}
+
+// Set replaces the leaf at index, an index of Len appends the leaf. Only the nodes on
+// the path of the leaf are hashed again.
+func (t *MerkleTree) Set(index int, leaf *big.Int) error {
+	if index < 0 || index > t.Len() {
+		return fmt.Errorf("leaf %d out of the %d leaves of the tree", index, t.Len())
+	}
+	if index >= 1<<t.depth {
+		return fmt.Errorf("%w : %d leaves", ErrMerkleTreeFull, 1<<t.depth)
+	}
+
+	t.setNode(0, index, new(big.Int).Set(leaf))
+	for l := 0; l < t.depth; l++ {
+		i := index >> l
+		parent, err := t.hashPair(t.node(l, i&^1), t.node(l, i|1))
+		if err != nil {
+			return err
+		}
+		t.setNode(l+1, i>>1, parent)
+	}
+	return nil
+}
+
+// Path returns the merkle path of the leaf at index against the current root
+func (t *MerkleTree) Path(index int) (*MerklePath, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, fmt.Errorf("leaf %d out of the %d leaves of the tree", index, t.Len())
+	}
+	path := &MerklePath{
+		Root:     t.Root(),
+		Leaf:     t.Leaf(index),
+		Index:    index,
+		Siblings: make([]*big.Int, t.depth),
+	}
+	for l := range path.Siblings {
+		path.Siblings[l] = t.node(l, (index>>l)^1)
+	}
+	return path, nil
+}
+
+func (t *MerkleTree) node(l, i int) *big.Int {
+	if i < len(t.nodes[l]) {
+		return t.nodes[l][i]
+	}
+	return t.zeros[l]
+}
+
+// setNode sets a node of a level, the level grows by at most one node since leaves are
+// appended one at a time
+func (t *MerkleTree) setNode(l, i int, value *big.Int) {
+	if i == len(t.nodes[l]) {
+		t.nodes[l] = append(t.nodes[l], value)
+		return
+	}
+	t.nodes[l][i] = value
+}
+
+// hashPair hashes two nodes as the circuit does, each one a field element block
+func (t *MerkleTree) hashPair(left, right *big.Int) (*big.Int, error) {
+	size := (t.curve.ScalarField().BitLen() + 7) / 8
+	data := make([]byte, 2*size)
+	left.FillBytes(data[:size])
+	right.FillBytes(data[size:])
+	return mimcSum(t.curve, data)
+}
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/consensys/gnark-crypto/ecc"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	mimc2 "github.com/consensys/gnark/std/hash/mimc"
+	"math/big"
+	"smart-contract-service/models"
+)
+
+const (
+	PaymentAlgorithm = "payment"
+	// PaymentBLS12377Algorithm proves payments on BLS12-377 so a settlement window of
+	// them can be folded into one BW6-761 proof
+	PaymentBLS12377Algorithm = "payment-bls12377"
+	// paymentDomain separates payment nullifiers from the other circuits ones
+	paymentDomain = 3
+)
+
+var (
+	ErrIntentMissing  = errors.New("no payment intent")
+	ErrIntentMismatch = errors.New("proof was made for another payment intent")
+)
+
+var ErrPaymentNotSupported = errors.New("proofs of the circuit cannot authorize a payment")
+
+func init() {
+	Register(&Definition{
+		Name:        PaymentAlgorithm,
+		Circuit:     func() frontend.Circuit { return &PaymentCircuit{} },
+		Assign:      assignPayment,
+		CheckPublic: checkHashPublic,
+		CheckIntent: checkPaymentIntent,
+		Nullifier:   paymentNullifier,
+		Artifact:    NewArtifact("payment"),
+		Curve:       ecc.BN254,
+		Curves:      mimcCurves,
+		Inputs:      paymentInputs,
+	})
+	Register(&Definition{
+		Name:        PaymentBLS12377Algorithm,
+		Circuit:     func() frontend.Circuit { return &PaymentCircuit{} },
+		Assign:      assignPayment,
+		CheckPublic: checkHashPublic,
+		CheckIntent: checkPaymentIntent,
+		Nullifier:   paymentNullifier,
+		Artifact:    NewArtifact("payment-bls12377"),
+		Curve:       ecc.BLS12_377,
+		Aggregate:   2,
+		Inputs:      paymentInputs,
+	})
+}
+
+var paymentInputs = []PublicInput{
+	hashInputs[0],
+	{Name: "Reference", Description: "partner reference number of the payment intent, as a field element"},
+	{Name: "Amount", Type: InputUint64, Description: "amount of the payment intent, in minor units"},
+	{Name: "Currency", Description: "currency of the payment intent, as a field element"},
+	{Name: "ExternalId", Description: "external id of the payment request, as a field element"},
+	{Name: "Nullifier", Description: "spent once per payment intent"},
+}
+
+// PaymentCircuit proves knowledge of the attributes behind the enrolled commitment for a
+// payment intent. The intent fields are public inputs the nullifier is derived from, so a
+// proof only authorizes the payment it was made for and authorizes it once.
+type PaymentCircuit struct {
+	KTP        frontend.Variable
+	Account    frontend.Variable
+	MotherName frontend.Variable
+	Salt       frontend.Variable
+	Hash       frontend.Variable `gnark:",public"`
+	Reference  frontend.Variable `gnark:",public"`
+	Amount     frontend.Variable `gnark:",public"`
+	Currency   frontend.Variable `gnark:",public"`
+	ExternalId frontend.Variable `gnark:",public"`
+	Nullifier  frontend.Variable `gnark:",public"`
+}
+
+func (circuit *PaymentCircuit) Define(api frontend.API) error {
+	mimc, err := mimc2.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	mimc.Write(circuit.KTP, circuit.Account, circuit.MotherName, circuit.Salt)
+	api.AssertIsEqual(circuit.Hash, mimc.Sum())
+
+	// the amount is bounded like the range circuit ones, it cannot wrap around the field
+	api.ToBinary(circuit.Amount, amountBits)
+	mimc.Reset()
+	mimc.Write(circuit.Reference, circuit.Amount, circuit.Currency, circuit.ExternalId)
+	intent := mimc.Sum()
+
+	mimc.Reset()
+	mimc.Write(circuit.Salt, intent, paymentDomain)
+	api.AssertIsEqual(circuit.Nullifier, mimc.Sum())
+	return nil
+}
+
+// IntentHash returns the decimal MiMC hash of the payment intent on the scalar field of
+// the curve, the amount is read in minor units so "10.0" and "10.00" are the same intent
+func IntentHash(curve ecc.ID, intent *models.PaymentIntent) (string, error) {
+	sum, err := intentSum(curve, intent)
+	if err != nil {
+		return "", err
+	}
+	return sum.String(), nil
+}
+
+// intentSum returns the MiMC hash of the intent inputs of the payment circuit
+func intentSum(curve ecc.ID, intent *models.PaymentIntent) (*big.Int, error) {
+	values, err := paymentValues(curve, intent)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, value := range values {
+		data = append(data, value.FillBytes(make([]byte, 32))...)
+	}
+	return mimcSum(curve, data)
+}
+
+// ConsentMessage returns the message a customer signs with its EdDSA key to consent to
+// a payment intent, the BN254 MiMC hash of the intent inputs of the payment circuit
+func ConsentMessage(intent *models.PaymentIntent) ([]byte, error) {
+	sum, err := intentSum(ecc.BN254, intent)
+	if err != nil {
+		return nil, err
+	}
+	return sum.FillBytes(make([]byte, 32)), nil
+}
+
+// SignConsent returns the hex signature of the customer consenting to the intent
+func SignConsent(key signature.Signer, intent *models.PaymentIntent) (string, error) {
+	message, err := ConsentMessage(intent)
+	if err != nil {
+		return "", err
+	}
+	sig, err := key.Sign(message, bn254.NewMiMC())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// VerifyConsent asserts consent is the signature of the intent by the customer key
+func VerifyConsent(key signature.PublicKey, intent *models.PaymentIntent, consent string) error {
+	message, err := ConsentMessage(intent)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(consent)
+	if err != nil {
+		return err
+	}
+	if ok, err := key.Verify(sig, message, bn254.NewMiMC()); err != nil || !ok {
+		return errors.New("signature does not match the intent")
+	}
+	return nil
+}
+
+// paymentValues returns the intent inputs of the payment circuit, in circuit order
+func paymentValues(curve ecc.ID, intent *models.PaymentIntent) ([]*big.Int, error) {
+	if intent == nil {
+		return nil, ErrIntentMissing
+	}
+	amount, err := ParseAmount(intent.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return []*big.Int{
+		new(big.Int).SetBytes(fieldBytes(curve, []byte(intent.PartnerReferenceNo))),
+		big.NewInt(amount),
+		new(big.Int).SetBytes(fieldBytes(curve, []byte(intent.Currency))),
+		new(big.Int).SetBytes(fieldBytes(curve, []byte(intent.ExternalId))),
+	}, nil
+}
+
+func assignPayment(in *WitnessInput) (frontend.Circuit, error) {
+	values, err := paymentValues(in.Curve, in.Intent)
+	if err != nil {
+		return nil, err
+	}
+	intent, err := intentSum(in.Curve, in.Intent)
+	if err != nil {
+		return nil, err
+	}
+
+	commitment, err := assignCommitment(in, intent.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	preimage, err := commitmentPreimage(in.Curve, in.Customer, in.Customer.CommitmentSalt)
+	if err != nil {
+		return nil, err
+	}
+	var nullifierData []byte
+	nullifierData = append(nullifierData, preimage[3]...)
+	nullifierData = append(nullifierData, intent.FillBytes(make([]byte, 32))...)
+	nullifierData = append(nullifierData, big.NewInt(paymentDomain).FillBytes(make([]byte, 32))...)
+	nullifier, err := mimcSum(in.Curve, nullifierData)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentCircuit{
+		KTP:        commitment.KTP,
+		Account:    commitment.Account,
+		MotherName: commitment.MotherName,
+		Salt:       commitment.Salt,
+		Hash:       commitment.Hash,
+		Reference:  values[0],
+		Amount:     values[1],
+		Currency:   values[2],
+		ExternalId: values[3],
+		Nullifier:  nullifier,
+	}, nil
+}
+
+// paymentPublic returns the public inputs of a payment witness, in circuit order
+func paymentPublic(publicWitness witness.Witness) ([]*big.Int, error) {
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	if len(public) != 6 {
+		return nil, errors.New("public witness is not a payment witness")
+	}
+	return public, nil
+}
+
+// checkPaymentIntent asserts the proof was made for the payment it is spent on, the
+// intent inputs follow the commitment in the public witness
+func checkPaymentIntent(publicWitness witness.Witness, in *WitnessInput) error {
+	values, err := paymentValues(in.Curve, in.Intent)
+	if err != nil {
+		return err
+	}
+	public, err := paymentPublic(publicWitness)
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		if public[i+1].Cmp(v) != 0 {
+			return ErrIntentMismatch
+		}
+	}
+	return nil
+}
+
+// paymentNullifier returns the nullifier of a verified payment witness, last of its inputs
+func paymentNullifier(publicWitness witness.Witness) (string, error) {
+	public, err := paymentPublic(publicWitness)
+	if err != nil {
+		return "", err
+	}
+	return public[5].String(), nil
+}
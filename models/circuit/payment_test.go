@@ -0,0 +1,44 @@
+package models
+
+import (
+	"smart-contract-service/models"
+	"testing"
+)
+
+func TestConsent(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	intent := &models.PaymentIntent{PartnerReferenceNo: "ref-1", Amount: "10.00", Currency: "IDR", ExternalId: "ext-1"}
+	consent, err := SignConsent(key, intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyConsent(key.Public(), intent, consent); err != nil {
+		t.Fatalf("consent refused with %v", err)
+	}
+
+	// the amount is read in minor units, both spellings are the same intent
+	same := *intent
+	same.Amount = "10.0"
+	if err = VerifyConsent(key.Public(), &same, consent); err != nil {
+		t.Fatalf("consent to %q refused with %v", same.Amount, err)
+	}
+
+	other := *intent
+	other.Amount = "10.01"
+	if err = VerifyConsent(key.Public(), &other, consent); err == nil {
+		t.Fatal("consent verified for another amount")
+	}
+	stranger, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyConsent(stranger.Public(), intent, consent); err == nil {
+		t.Fatal("consent verified under another key")
+	}
+	if err = VerifyConsent(key.Public(), intent, "zz"); err == nil {
+		t.Fatal("malformed consent verified")
+	}
+}
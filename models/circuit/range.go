@@ -0,0 +1,292 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	mimc2 "github.com/consensys/gnark/std/hash/mimc"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	RangeAlgorithm = "range"
+	// amountBits bounds amounts and balances so comparisons cannot wrap around the field
+	amountBits = 64
+	// rangeDomain separates range nullifiers from the other circuits ones
+	rangeDomain = 2
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount is not a positive decimal of at most 2 fraction digits")
+	ErrRangeMissing        = errors.New("partner accepts no range proof")
+	ErrInvalidRange        = errors.New("partner range is not a range of amounts")
+	ErrAmountOutOfRange    = errors.New("amount is out of the partner range")
+	ErrBalanceInsufficient = errors.New("balance is insufficient for the amount")
+	ErrBalanceMismatch     = errors.New("proof was made for another balance")
+)
+
+func init() {
+	Register(&Definition{
+		Name:        RangeAlgorithm,
+		Circuit:     func() frontend.Circuit { return &RangeCircuit{} },
+		Assign:      assignRange,
+		CheckPublic: checkRangePublic,
+		CheckIntent: checkRangeIntent,
+		Nullifier:   rangeNullifier,
+		Artifact:    NewArtifact("range"),
+		Curves:      mimcCurves,
+		Inputs: []PublicInput{
+			{Name: "Min", Type: InputUint64, Description: "least amount the partner accepts, in minor units"},
+			{Name: "Max", Type: InputUint64, Description: "greatest amount the partner accepts, in minor units"},
+			{Name: "BalanceCommitment", Description: "customer balance blinded by the customer salt and the blinding"},
+			{Name: "AmountCommitment", Description: "amount of the payment intent blinded by the customer salt and the blinding"},
+			{Name: "Context", Description: "hash of the payment intent the proof authorizes"},
+			{Name: "Blinding", Description: "drawn for each proof, so proofs of one balance cannot be linked"},
+			{Name: "Nullifier", Description: "spent once per payment intent"},
+		},
+	})
+}
+
+// AmountRange is the range of amounts, in minor units, a partner accepts
+type AmountRange struct {
+	Min int64
+	Max int64
+}
+
+// Check asserts the range holds an amount, a range with Max below Min accepts none and
+// the circuit would only fail to solve
+func (r *AmountRange) Check() error {
+	if r.Min < 0 || r.Max < r.Min {
+		return fmt.Errorf("%w : [%d, %d]", ErrInvalidRange, r.Min, r.Max)
+	}
+	return nil
+}
+
+// RangeCircuit proves a hidden amount lies in the partner range and is covered by the
+// customer balance. The balance is only public as a commitment blinded by the customer
+// salt and a blinding drawn for the proof, the amount as a commitment to the payment
+// intent, which the service opens.
+type RangeCircuit struct {
+	Amount            frontend.Variable
+	Balance           frontend.Variable
+	Salt              frontend.Variable
+	Min               frontend.Variable `gnark:",public"`
+	Max               frontend.Variable `gnark:",public"`
+	BalanceCommitment frontend.Variable `gnark:",public"`
+	AmountCommitment  frontend.Variable `gnark:",public"`
+	Context           frontend.Variable `gnark:",public"`
+	Blinding          frontend.Variable `gnark:",public"`
+	Nullifier         frontend.Variable `gnark:",public"`
+}
+
+func (circuit *RangeCircuit) Define(api frontend.API) error {
+	for _, v := range []frontend.Variable{circuit.Amount, circuit.Balance, circuit.Min, circuit.Max} {
+		api.ToBinary(v, amountBits)
+	}
+	api.AssertIsLessOrEqual(circuit.Min, circuit.Amount)
+	api.AssertIsLessOrEqual(circuit.Amount, circuit.Max)
+	api.AssertIsLessOrEqual(circuit.Amount, circuit.Balance)
+
+	mimc, err := mimc2.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	mimc.Write(circuit.Balance, circuit.Salt, circuit.Blinding)
+	api.AssertIsEqual(circuit.BalanceCommitment, mimc.Sum())
+
+	mimc.Reset()
+	mimc.Write(circuit.Amount, circuit.Salt, circuit.Blinding, circuit.Context)
+	api.AssertIsEqual(circuit.AmountCommitment, mimc.Sum())
+
+	mimc.Reset()
+	mimc.Write(circuit.Salt, circuit.Context, rangeDomain)
+	api.AssertIsEqual(circuit.Nullifier, mimc.Sum())
+	return nil
+}
+
+// ParseAmount returns a positive payment amount such as "10000.00" in minor units
+func ParseAmount(value string) (int64, error) {
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" || len(fraction) > 2 || strings.ContainsAny(value, "+-") {
+		return 0, fmt.Errorf("%w : %q", ErrInvalidAmount, value)
+	}
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", 2-len(fraction)), 10, 64)
+	if err != nil || minor <= 0 {
+		return 0, fmt.Errorf("%w : %q", ErrInvalidAmount, value)
+	}
+	return minor, nil
+}
+
+// rangeValues returns the field elements of the range circuit, as 32 bytes blocks: the
+// amount of the intent, the customer salt and the context bound to the intent
+func rangeValues(in *WitnessInput) (amount int64, salt, context []byte, err error) {
+	if in.Intent == nil {
+		return 0, nil, nil, ErrIntentMissing
+	}
+	if in.Customer.CommitmentSalt == "" {
+		return 0, nil, nil, ErrCommitmentMissing
+	}
+	if amount, err = ParseAmount(in.Intent.Amount); err != nil {
+		return 0, nil, nil, err
+	}
+	preimage, err := commitmentPreimage(in.Curve, in.Customer, in.Customer.CommitmentSalt)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	intent, err := intentSum(in.Curve, in.Intent)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return amount, preimage[3], intent.FillBytes(make([]byte, 32)), nil
+}
+
+// balanceCommitment returns the commitment to the customer balance blinded by its salt
+// and the blinding of the proof
+func balanceCommitment(in *WitnessInput, salt []byte, blinding *big.Int) (*big.Int, error) {
+	var data []byte
+	data = append(data, big.NewInt(in.Customer.Balance).FillBytes(make([]byte, 32))...)
+	data = append(data, salt...)
+	data = append(data, blinding.FillBytes(make([]byte, 32))...)
+	return mimcSum(in.Curve, data)
+}
+
+// amountCommitment returns the commitment to the amount of the payment intent
+func amountCommitment(in *WitnessInput, amount int64, salt []byte, blinding *big.Int, context []byte) (*big.Int, error) {
+	var data []byte
+	data = append(data, big.NewInt(amount).FillBytes(make([]byte, 32))...)
+	data = append(data, salt...)
+	data = append(data, blinding.FillBytes(make([]byte, 32))...)
+	data = append(data, context...)
+	return mimcSum(in.Curve, data)
+}
+
+func assignRange(in *WitnessInput) (frontend.Circuit, error) {
+	if in.Range == nil || in.Range.Max == 0 {
+		return nil, ErrRangeMissing
+	}
+	if err := in.Range.Check(); err != nil {
+		return nil, err
+	}
+	amount, salt, context, err := rangeValues(in)
+	if err != nil {
+		return nil, err
+	}
+	// the solver would fail alike, the reason is only known here
+	if amount < in.Range.Min || amount > in.Range.Max {
+		return nil, fmt.Errorf("%w : %d not in [%d, %d]", ErrAmountOutOfRange, amount, in.Range.Min, in.Range.Max)
+	}
+	if in.Customer.Balance < amount {
+		return nil, ErrBalanceInsufficient
+	}
+
+	blinding, err := rand.Int(rand.Reader, in.Curve.ScalarField())
+	if err != nil {
+		return nil, err
+	}
+	balance, err := balanceCommitment(in, salt, blinding)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := amountCommitment(in, amount, salt, blinding, context)
+	if err != nil {
+		return nil, err
+	}
+	var nullifierData []byte
+	nullifierData = append(nullifierData, salt...)
+	nullifierData = append(nullifierData, context...)
+	nullifierData = append(nullifierData, big.NewInt(rangeDomain).FillBytes(make([]byte, 32))...)
+	nullifier, err := mimcSum(in.Curve, nullifierData)
+	if err != nil {
+		return nil, err
+	}
+	return &RangeCircuit{
+		Amount:            amount,
+		Balance:           in.Customer.Balance,
+		Salt:              salt,
+		Min:               in.Range.Min,
+		Max:               in.Range.Max,
+		BalanceCommitment: balance,
+		AmountCommitment:  committed,
+		Context:           context,
+		Blinding:          blinding,
+		Nullifier:         nullifier,
+	}, nil
+}
+
+// rangePublic returns the public inputs of a range witness, in circuit order
+func rangePublic(publicWitness witness.Witness) ([]*big.Int, error) {
+	public, err := publicValues(publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	if len(public) != 7 {
+		return nil, errors.New("public witness is not a range witness")
+	}
+	return public, nil
+}
+
+// checkRangePublic asserts the proof was made for the current balance of the customer
+func checkRangePublic(publicWitness witness.Witness, in *WitnessInput) error {
+	public, err := rangePublic(publicWitness)
+	if err != nil {
+		return err
+	}
+	if in.Customer.CommitmentSalt == "" {
+		return ErrCommitmentMissing
+	}
+	preimage, err := commitmentPreimage(in.Curve, in.Customer, in.Customer.CommitmentSalt)
+	if err != nil {
+		return err
+	}
+	balance, err := balanceCommitment(in, preimage[3], public[5])
+	if err != nil {
+		return err
+	}
+	if public[2].Cmp(balance) != 0 {
+		return ErrBalanceMismatch
+	}
+	return nil
+}
+
+// checkRangeIntent asserts the proof was made for the amount of the payment it is spent
+// on and the range of its partner
+func checkRangeIntent(publicWitness witness.Witness, in *WitnessInput) error {
+	if in.Range == nil || in.Range.Max == 0 {
+		return ErrRangeMissing
+	}
+	if err := in.Range.Check(); err != nil {
+		return err
+	}
+	public, err := rangePublic(publicWitness)
+	if err != nil {
+		return err
+	}
+	amount, salt, context, err := rangeValues(in)
+	if err != nil {
+		return err
+	}
+	if public[0].Cmp(big.NewInt(in.Range.Min)) != 0 || public[1].Cmp(big.NewInt(in.Range.Max)) != 0 {
+		return fmt.Errorf("%w : proof was made for [%s, %s]", ErrAmountOutOfRange, public[0], public[1])
+	}
+	committed, err := amountCommitment(in, amount, salt, public[5], context)
+	if err != nil {
+		return err
+	}
+	if public[3].Cmp(committed) != 0 || public[4].Cmp(new(big.Int).SetBytes(context)) != 0 {
+		return ErrIntentMismatch
+	}
+	return nil
+}
+
+// rangeNullifier returns the nullifier of a verified range witness, last of its inputs
+func rangeNullifier(publicWitness witness.Witness) (string, error) {
+	public, err := rangePublic(publicWitness)
+	if err != nil {
+		return "", err
+	}
+	return public[6].String(), nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	for value, minor := range map[string]int64{"10000.00": 1000000, "0.01": 1, "12.5": 1250, "7": 700} {
+		got, err := ParseAmount(value)
+		if err != nil || got != minor {
+			t.Fatalf("%q parsed as %d with %v, expected %d", value, got, err, minor)
+		}
+	}
+	for _, value := range []string{"0", "0.00", "-1.00", "+1.00", "1.001", ".50", "", "ten"} {
+		if _, err := ParseAmount(value); !errors.Is(err, ErrInvalidAmount) {
+			t.Fatalf("%q parsed with %v", value, err)
+		}
+	}
+}
+
+func TestAmountRangeCheck(t *testing.T) {
+	for _, r := range []AmountRange{{Min: 0, Max: 1}, {Min: 100, Max: 100}} {
+		if err := r.Check(); err != nil {
+			t.Fatalf("%+v refused with %v", r, err)
+		}
+	}
+	for _, r := range []AmountRange{{Min: 200, Max: 100}, {Min: -1, Max: 100}} {
+		if err := r.Check(); !errors.Is(err, ErrInvalidRange) {
+			t.Fatalf("%+v checked with %v", r, err)
+		}
+	}
+
+	in := &WitnessInput{Range: &AmountRange{Min: 200, Max: 100}}
+	if _, err := assignRange(in); !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("range %+v assigned with %v", in.Range, err)
+	}
+}
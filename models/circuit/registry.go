@@ -0,0 +1,268 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std"
+	"math/big"
+	"path"
+	"smart-contract-service/internal"
+	"smart-contract-service/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Artifact locates the files written by the circuit setup
+type Artifact struct {
+	R1cs     string
+	Pk       string
+	Vk       string
+	Solidity string
+}
+
+// NewArtifact returns the artifact locations of a circuit stored under internal.CircuitDir
+func NewArtifact(baseName string) Artifact {
+	return artifactAt(path.Join(internal.CircuitDir, baseName))
+}
+
+// artifactAt returns the artifact locations of a circuit from their path without extension
+func artifactAt(base string) Artifact {
+	return Artifact{
+		R1cs:     base + ".r1cs",
+		Pk:       base + ".pk",
+		Vk:       base + ".vk",
+		Solidity: base + ".sol",
+	}
+}
+
+// For returns the artifact locations of the backend, groth16 artifacts keep the
+// base locations and plonk artifacts are stored next to them
+func (a Artifact) For(b backend.ID) Artifact {
+	if b != backend.PLONK {
+		return a
+	}
+	base := strings.TrimSuffix(a.R1cs, path.Ext(a.R1cs)) + ".plonk"
+	return Artifact{
+		R1cs:     base + ".scs",
+		Pk:       base + ".pk",
+		Vk:       base + ".vk",
+		Solidity: base + ".sol",
+	}
+}
+
+// WitnessInput is what the service knows about the customer a proof is made for
+type WitnessInput struct {
+	Customer *models.Customer
+	// SigningKey is the registered EdDSA key, nil when the customer holds it
+	SigningKey signature.Signer
+	// PublicKey is the registered EdDSA public key, nil when none is registered
+	PublicKey signature.PublicKey
+	// Issuer is the service key credentials are signed with, nil on the customer side
+	Issuer signature.Signer
+	// Credential is the issued credential, issued on the fly with Issuer when nil
+	Credential *models.Credential
+	// Policy is what credential proofs are made for and checked against
+	Policy *CredentialPolicy
+	// Curve is the curve of the circuit the witness is built for
+	Curve ecc.ID
+	// Context is what the proof is made for, such as a payment reference, circuits with a
+	// nullifier use a random nonce when empty
+	Context string
+	// Intent is the payment the proof authorizes, for circuits checking it
+	Intent *models.PaymentIntent
+	// Range is the range of amounts the partner of the intent accepts, nil when none
+	Range *AmountRange
+	// Membership is the merkle path of the customer commitment, nil when not in the tree
+	Membership *MerklePath
+	// KnownRoot tells whether a membership root was published, nil when no tree is kept
+	KnownRoot func(root *big.Int) bool
+}
+
+// Definition is a circuit registered under an algorithm name
+type Definition struct {
+	// Name is the algorithm name used by the API
+	Name string
+	// Circuit returns an empty circuit used for compilation
+	Circuit func() frontend.Circuit
+	// Assign builds the full witness assignment of a customer
+	Assign func(in *WitnessInput) (frontend.Circuit, error)
+	// CheckPublic, when set, binds a verified public witness to the customer
+	CheckPublic func(publicWitness witness.Witness, in *WitnessInput) error
+	// Nullifier, when set, returns the nullifier carried by a verified public witness, a
+	// proof is spent once per nullifier
+	Nullifier func(publicWitness witness.Witness) (string, error)
+	// CheckIntent, when set, binds a verified public witness to the payment it is spent on
+	CheckIntent func(publicWitness witness.Witness, in *WitnessInput) error
+	// Inputs documents the public inputs by name, the inputs of an array once under the
+	// array name, undocumented inputs are field elements
+	Inputs []PublicInput
+	// Artifact locates the compiled circuit and its groth16 keys, see Artifact.For
+	Artifact Artifact
+	// Curve the circuit is compiled and proved over, BN254 when not set
+	Curve ecc.ID
+	// Curves the circuit can be configured on instead of Curve, see Configure
+	Curves []ecc.ID
+	// Backend proves the circuit, groth16 when not set
+	Backend backend.ID
+	// Legacy backends still verify proofs of the circuit while migrating to Backend
+	Legacy []backend.ID
+	// Deprecated names the successor algorithm of a circuit kept only for existing callers
+	Deprecated string
+	// Aggregate, when set, registers an aggregator folding up to that many proofs of the
+	// circuit into one, the circuit must be proved with groth16 on BLS12-377
+	Aggregate int
+	// Aggregates is the circuit whose proofs an aggregator folds, nil for other circuits
+	Aggregates *Definition
+	// Anonymous proofs do not reveal the customer, their tokens carry no customer id and
+	// CheckPublic only gets what the service publishes
+	Anonymous bool
+}
+
+// Backends returns the backend proofs are made with followed by the legacy ones
+func (def *Definition) Backends() []backend.ID {
+	return append([]backend.ID{def.Backend}, def.Legacy...)
+}
+
+// Accepts reports whether proofs made with the backend are verified
+func (def *Definition) Accepts(b backend.ID) bool {
+	for _, id := range def.Backends() {
+		if id == b {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrAlgorithmNotFound = errors.New("algorithm not found")
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Definition)
+	// aggregators are indexed by the name of the circuit they fold
+	aggregators = make(map[string]*Definition)
+)
+
+func init() {
+	// circuits are proved from their serialized constraint system, which does
+	// not carry the std hints the solver needs
+	std.RegisterHints()
+}
+
+// Register adds a circuit definition, it panics if the name is already taken
+func Register(def *Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[def.Name]; ok {
+		panic(fmt.Sprintf("circuit already registered : %s", def.Name))
+	}
+	if def.Backend == backend.UNKNOWN {
+		def.Backend = backend.GROTH16
+	}
+	if def.Curve == ecc.UNKNOWN {
+		def.Curve = ecc.BN254
+	}
+	if err := checkCurve(def.Curve); err != nil {
+		panic(fmt.Sprintf("circuit %s : %s", def.Name, err))
+	}
+	if def.Aggregate > 0 {
+		agg, err := newAggregator(def)
+		if err != nil {
+			panic(fmt.Sprintf("circuit %s : %s", def.Name, err))
+		}
+		aggregators[def.Name] = agg
+	}
+	registry[def.Name] = def
+}
+
+// Configure moves circuits to the curve configured for them, by algorithm name. It runs
+// once at startup before any key is loaded, the service and the partner provers must
+// agree on the curves, which the manifest and the circuit schema publish.
+func Configure(curves map[string]string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for name, curveName := range curves {
+		def, ok := registry[name]
+		if !ok {
+			return fmt.Errorf("%w : %s", ErrAlgorithmNotFound, name)
+		}
+		curve, err := ParseCurve(curveName)
+		if err != nil {
+			return err
+		}
+		if curve == def.Curve {
+			continue
+		}
+		if !def.configurable(curve) {
+			return fmt.Errorf("%w : %s cannot be configured on %s", ErrCurveNotSupported, name, curve)
+		}
+		def.Curve = curve
+		if def.Aggregate > 0 {
+			agg, err := newAggregator(def)
+			if err != nil {
+				return fmt.Errorf("circuit %s : %w", name, err)
+			}
+			aggregators[def.Name] = agg
+		}
+	}
+	return nil
+}
+
+func (def *Definition) configurable(curve ecc.ID) bool {
+	for _, id := range def.Curves {
+		if id == curve {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the circuit registered under the algorithm name
+func Lookup(name string) (*Definition, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w : %s", ErrAlgorithmNotFound, name)
+	}
+	return def, nil
+}
+
+// LookupAggregator returns the aggregator of the circuit registered under the algorithm
+// name, aggregators are not returned by Lookup as they do not prove customers
+func LookupAggregator(name string) (*Definition, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	agg, ok := aggregators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w : %s", ErrAggregateNotSupported, name)
+	}
+	return agg, nil
+}
+
+// Definitions returns every registered circuit and aggregator ordered by name
+func Definitions() []*Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]*Definition, 0, len(registry)+len(aggregators))
+	for _, def := range registry {
+		defs = append(defs, def)
+	}
+	for _, agg := range aggregators {
+		defs = append(defs, agg)
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+	return defs
+}
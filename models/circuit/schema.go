@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/schema"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// Types of the public inputs, every input is a field element, the type tells how it reads
+const (
+	InputField  = "field"
+	InputUint64 = "uint64"
+	InputBool   = "bool"
+	// InputDate is a date written as the yyyymmdd integer
+	InputDate = "date"
+)
+
+var ErrInvalidPublicInput = errors.New("invalid public input")
+
+// PublicInput describes an input of the public witness
+type PublicInput struct {
+	// Name is the field path in the circuit, array elements and nested fields joined by _
+	Name        string
+	Type        string
+	Description string
+}
+
+// input returns the documentation of the named input, inputs of an array are documented
+// once under the array name
+func (def *Definition) input(name string) (PublicInput, bool) {
+	base := name
+	if i := strings.LastIndex(name, "_"); i >= 0 && strings.Trim(name[i+1:], "0123456789") == "" {
+		base = name[:i]
+	}
+	for _, in := range def.Inputs {
+		if in.Name == name || in.Name == base {
+			return in, true
+		}
+	}
+	return PublicInput{}, false
+}
+
+// PublicSchema returns the public inputs of the circuit in witness order
+func PublicSchema(def *Definition) ([]PublicInput, error) {
+	var inputs []PublicInput
+	variable := reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+	_, err := schema.Walk(def.Circuit(), variable, func(leaf schema.LeafInfo, _ reflect.Value) error {
+		if leaf.Visibility != schema.Public {
+			return nil
+		}
+		name := leaf.FullName()
+		input, ok := def.input(name)
+		if !ok || input.Type == "" {
+			input.Type = InputField
+		}
+		input.Name = name
+		inputs = append(inputs, input)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// EncodePublic returns the public witness of the circuit as decimal values by input name
+func EncodePublic(def *Definition, publicWitness witness.Witness) (map[string]string, error) {
+	inputs, err := PublicSchema(def)
+	if err != nil {
+		return nil, err
+	}
+	values, err := publicValues(publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(inputs) {
+		return nil, fmt.Errorf("%w : %d inputs for %d in the %s circuit", ErrInvalidPublicInput, len(values), len(inputs), def.Name)
+	}
+	public := make(map[string]string, len(inputs))
+	for i, input := range inputs {
+		public[input.Name] = values[i].String()
+	}
+	return public, nil
+}
+
+// DecodePublic builds the public witness of the circuit from decimal values by input name,
+// every input must be given and be an element of the scalar field
+func DecodePublic(def *Definition, public map[string]string) (witness.Witness, error) {
+	inputs, err := PublicSchema(def)
+	if err != nil {
+		return nil, err
+	}
+	modulus := def.Curve.ScalarField()
+	values := make([]*big.Int, len(inputs))
+	for i, input := range inputs {
+		value, ok := public[input.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w : %s is missing", ErrInvalidPublicInput, input.Name)
+		}
+		v, ok := new(big.Int).SetString(value, 10)
+		if !ok || v.Sign() < 0 || v.Cmp(modulus) >= 0 {
+			return nil, fmt.Errorf("%w : %s is not a field element", ErrInvalidPublicInput, input.Name)
+		}
+		values[i] = v
+	}
+	if len(public) != len(inputs) {
+		for name := range public {
+			if !hasInput(inputs, name) {
+				return nil, fmt.Errorf("%w : %s is not an input of the %s circuit", ErrInvalidPublicInput, name, def.Name)
+			}
+		}
+	}
+
+	publicWitness, err := witness.New(modulus)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan any, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	if err = publicWitness.Fill(len(values), 0, ch); err != nil {
+		return nil, err
+	}
+	return publicWitness, nil
+}
+
+func hasInput(inputs []PublicInput, name string) bool {
+	for _, input := range inputs {
+		if input.Name == name {
+			return true
+		}
+	}
+	return false
+}
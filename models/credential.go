@@ -0,0 +1,12 @@
+package models
+
+// Credential is a statement about a customer signed by the service issuer key,
+// the credential circuit proves policies over it without disclosing the attributes
+type Credential struct {
+	CustomerId string `json:"customerId"`
+	Commitment string `json:"commitment"` // enrolled customer commitment the credential is bound to
+	BirthDate  int    `json:"birthDate"`  // yyyymmdd
+	Branch     string `json:"branch"`
+	Issuer     string `json:"issuer"`    // hex of the compressed issuer public key
+	Signature  string `json:"signature"` // hex of the issuer EdDSA signature
+}
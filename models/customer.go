@@ -6,15 +6,24 @@ import (
 )
 
 type Customer struct {
-	Id         string         `json:"id" gorm:"primary_key"`
-	KTP        string         `json:"ktp" gorm:"column:ktp;uniqueIndex:customers_ktp_uindex"`
-	NoRek      string         `json:"account" gorm:"column:account;uniqueIndex:customers_account_uindex"`
-	Name       string         `json:"name" gorm:"column:customer_name"`
-	Branch     string         `json:"branch" gorm:"column:branch"`
-	MotherName string         `json:"motherName" gorm:"column:mother_name"`
-	CreatedAt  *time.Time     `json:"createdAt,omitempty"`
-	UpdatedAt  *time.Time     `json:"updatedAt,omitempty"`
-	DeletedAt  gorm.DeletedAt `json:"deletedAt,omitempty" sql:"index"`
+	Id         string     `json:"id" gorm:"primary_key"`
+	KTP        string     `json:"ktp" gorm:"column:ktp;uniqueIndex:customers_ktp_uindex"`
+	NoRek      string     `json:"account" gorm:"column:account;uniqueIndex:customers_account_uindex"`
+	Name       string     `json:"name" gorm:"column:customer_name"`
+	Branch     string     `json:"branch" gorm:"column:branch"`
+	MotherName string     `json:"motherName" gorm:"column:mother_name"`
+	BirthDate  *time.Time `json:"birthDate,omitempty" gorm:"column:birth_date"`
+	// MiMC commitment over KTP, account and mother name proven by the hash circuit
+	Commitment     string         `json:"commitment,omitempty" gorm:"column:commitment"`
+	CommitmentSalt string         `json:"-" gorm:"column:commitment_salt"`
+	Balance        int64          `json:"-" gorm:"column:balance"` // minor units, only proven in range
+	CreatedAt      *time.Time     `json:"createdAt,omitempty"`
+	UpdatedAt      *time.Time     `json:"updatedAt,omitempty"`
+	DeletedAt      gorm.DeletedAt `json:"deletedAt,omitempty" sql:"index"`
+
+	// Seq is assigned by the database on insert, the customer holds leaf Seq-1 of the
+	// membership tree whatever order rows are committed in
+	Seq int64 `json:"-" gorm:"column:seq;autoIncrement;uniqueIndex:customers_seq_uindex"`
 }
 
 func (Customer) TableName() string {
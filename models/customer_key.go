@@ -0,0 +1,22 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// CustomerKey is the EdDSA (BabyJubJub) key pair registered for a customer,
+// PrivateKey is empty when the customer keeps the private key on its side
+type CustomerKey struct {
+	Id         string         `json:"id" gorm:"primary_key"`
+	CustomerId string         `json:"customerId" gorm:"column:customer_id;uniqueIndex:customer_keys_customer_id_uindex"`
+	PublicKey  string         `json:"publicKey" gorm:"column:public_key"`
+	PrivateKey string         `json:"-" gorm:"column:private_key"`
+	CreatedAt  *time.Time     `json:"createdAt,omitempty"`
+	UpdatedAt  *time.Time     `json:"updatedAt,omitempty"`
+	DeletedAt  gorm.DeletedAt `json:"deletedAt,omitempty" sql:"index"`
+}
+
+func (CustomerKey) TableName() string {
+	return "customer_keys"
+}
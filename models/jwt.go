@@ -9,3 +9,15 @@ type JwtCustomClaims struct {
 	ExpireAt string `json:"expireAt"`
 	jwt.StandardClaims
 }
+
+// ProofClaims embeds a proof and its public witness in a token signed by the service,
+// the subject is the customer id
+type ProofClaims struct {
+	Circuit       string `json:"circuit"`
+	Backend       string `json:"backend,omitempty"` // groth16 when empty
+	Curve         string `json:"curve,omitempty"`   // BN254 when empty
+	Partner       string `json:"partner,omitempty"` // partner the token was issued to
+	Proof         []byte `json:"proof"`
+	PublicWitness []byte `json:"publicWitness"`
+	jwt.StandardClaims
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// SpentNullifier records a proof used to authorize a payment, a nullifier is spent once per circuit.
+// A revoked proof records its nullifier as spent and revoked.
+type SpentNullifier struct {
+	Circuit    string     `json:"circuit" gorm:"primary_key;column:circuit;uniqueIndex:spent_nullifiers_context_uindex"`
+	Nullifier  string     `json:"nullifier" gorm:"primary_key;column:nullifier"`
+	CustomerId string     `json:"customerId" gorm:"column:customer_id;uniqueIndex:spent_nullifiers_context_uindex"`
+	Revoked    bool       `json:"revoked" gorm:"column:revoked"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+
+	// Context is the hash of the payment intent a spent proof paid, null for revocations.
+	// The customer pays an intent once per circuit whichever salt the nullifier was
+	// derived from, enrolling again rotates the salt.
+	Context *string `json:"context,omitempty" gorm:"column:context;uniqueIndex:spent_nullifiers_context_uindex"`
+}
+
+func (SpentNullifier) TableName() string {
+	return "spent_nullifiers"
+}
@@ -10,6 +10,8 @@ type Partners struct {
 	ReferenceNo string         `json:"referenceNo" gorm:"column:reference_no;uniqueIndex:partner_reference_no_uindex"`
 	Username    string         `json:"username" gorm:"column:username;uniqueIndex:partner_username_uindex"`
 	Password    string         `json:"password"`
+	MinAmount   int64          `json:"minAmount,omitempty" gorm:"column:min_amount"` // minor units, see MaxAmount
+	MaxAmount   int64          `json:"maxAmount,omitempty" gorm:"column:max_amount"` // minor units, 0 accepts no range proof
 	CreatedAt   *time.Time     `json:"createdAt,omitempty"`
 	UpdatedAt   *time.Time     `json:"updatedAt,omitempty"`
 	DeletedAt   gorm.DeletedAt `json:"deletedAt,omitempty" sql:"index"`
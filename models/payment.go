@@ -36,3 +36,11 @@ func (a *JSONB) Scan(value interface{}) error {
 	}
 	return json.Unmarshal(b, &a)
 }
+
+// PaymentIntent is the payment a proof authorizes, the amount and currency as sent in the payment request
+type PaymentIntent struct {
+	PartnerReferenceNo string `json:"partnerReferenceNo"`
+	Amount             string `json:"amount"`
+	Currency           string `json:"currency"`
+	ExternalId         string `json:"externalId"`
+}
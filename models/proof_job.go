@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ProofJobQueued  = "queued"
+	ProofJobRunning = "running"
+	ProofJobDone    = "done"
+	ProofJobFailed  = "failed"
+)
+
+// ProofJob is a proof generated in the background, Hash is the proof token once done
+type ProofJob struct {
+	Id         string     `json:"id" gorm:"primary_key"`
+	Algo       string     `json:"algo" gorm:"column:algo"`
+	CustomerId string     `json:"customerId" gorm:"column:customer_id"`
+	PartnerId  string     `json:"-" gorm:"column:partner_id;index:proof_jobs_partner_id_index"` // only this partner reads the job
+	Status     string     `json:"status" gorm:"column:status;index:proof_jobs_status_index"`
+	Hash       string     `json:"hash,omitempty" gorm:"column:hash"`
+	Error      string     `json:"error,omitempty" gorm:"column:error"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+func (ProofJob) TableName() string {
+	return "proof_jobs"
+}
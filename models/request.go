@@ -1,13 +1,97 @@
 package models
 
 type CustomerIdRequest struct {
-	Algo string `query:"algo"`
-	Id   string `query:"id"`
+	Algo    string `query:"algo"`
+	Id      string `query:"id"`
+	Context string `query:"context"` // what the proof is for, bound to its nullifier
+}
+
+type PaymentProofRequest struct {
+	Algo               string `json:"algo,omitempty"` // payment when empty, payment-bls12377 to aggregate the proofs
+	CustomerId         string `json:"customerId"`
+	PartnerReferenceNo string `json:"partnerReferenceNo"`
+	Amount             struct {
+		Value    string `json:"value"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	ExternalId string `json:"externalId"` // X-EXTERNAL-ID of the payment request
+	// Consent is the hex EdDSA signature of the intent by the key the customer holds,
+	// see models/circuit.SignConsent
+	Consent   string `json:"consent"`
+	PartnerId string `json:"-"` // partner of the access token
+}
+
+type ProofJobRequest struct {
+	Algo       string `json:"algo"`
+	CustomerId string `json:"customerId"`
+	PartnerId  string `json:"-"` // partner of the access token
+}
+
+type ProofJobIdRequest struct {
+	Id        string `param:"id"`
+	PartnerId string `json:"-"` // partner of the access token
+}
+
+type BatchProofRequest struct {
+	Algo        string   `json:"algo"`
+	CustomerIds []string `json:"customerIds"`
+	PartnerId   string   `json:"-"` // partner of the access token
 }
 
 type ProofRequest struct {
-	Algo  string `json:"algo"`
-	Proof string `json:"proof"`
+	Algo      string `json:"algo"`
+	Proof     string `json:"proof"`
+	PartnerId string `json:"-"` // partner of the access token
+}
+
+type BatchVerifyRequest struct {
+	Proofs []ProofRequest `json:"proofs"`
+}
+
+type AggregateProofRequest struct {
+	Algo   string   `json:"algo"`
+	Proofs []string `json:"proofs"` // proof tokens of the circuit
+}
+
+type AggregateVerifyRequest struct {
+	Algo          string `json:"algo"`
+	Proof         string `json:"proof"`         // base64 of the gnark binary aggregated proof
+	PublicWitness string `json:"publicWitness"` // base64 of the gnark binary public witness
+}
+
+type ExternalProofRequest struct {
+	Algo          string `json:"algo"`
+	Backend       string `json:"backend,omitempty"` // groth16 when empty
+	Curve         string `json:"curve,omitempty"`   // BN254 when empty
+	CustomerId    string `json:"customerId"`
+	Proof         string `json:"proof"`         // base64 of the gnark binary proof
+	PublicWitness string `json:"publicWitness"` // base64 of the gnark binary public witness
+	// Public is the public witness as decimal inputs by name, used when PublicWitness is empty
+	Public    map[string]string `json:"public,omitempty"`
+	PartnerId string            `json:"-"` // partner of the access token
+}
+
+type CircuitSchemaRequest struct {
+	Algo string `param:"algo"`
+}
+
+type CircuitArtifactRequest struct {
+	Algo     string `param:"algo"`
+	Artifact string `param:"artifact"`
+	Backend  string `query:"backend"` // backend the circuit proves with when empty
+}
+
+type KeyRequest struct {
+	CustomerId string `json:"customerId"`
+	PublicKey  string `json:"publicKey,omitempty"` // hex of the compressed public key, generated by the service when empty
+}
+
+type CommitmentRequest struct {
+	CustomerId string `json:"customerId"`
+}
+
+type CredentialRequest struct {
+	CustomerId string `json:"customerId"`
 }
 
 type RequestHeader struct {
@@ -11,7 +11,90 @@ type Response struct {
 }
 
 type ProofResponse struct {
-	Hash string `json:"hash"`
+	Hash      string            `json:"hash"`
+	Nullifier string            `json:"nullifier,omitempty"` // circuits deriving a nullifier only
+	Public    map[string]string `json:"public,omitempty"`    // public inputs by name, in decimal
+	ExpiresAt string            `json:"expiresAt"`           // RFC 3339, the token is refused after
+}
+
+type BatchProofResponse struct {
+	Algo    string             `json:"algo"`
+	Proved  int                `json:"proved"`
+	Failed  int                `json:"failed"`
+	Results []BatchProofResult `json:"results"` // in the order of the requested customer ids
+}
+
+type BatchProofResult struct {
+	CustomerId string `json:"customerId"`
+	Hash       string `json:"hash,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type BatchVerifyResponse struct {
+	Valid   int                  `json:"valid"`
+	Invalid int                  `json:"invalid"`
+	Results []VerificationResult `json:"results"` // in the order of the requested proofs
+}
+
+// reasons a proof is not valid, see VerificationResult
+const (
+	ReasonUnknownAlgorithm    = "unknown_algorithm"
+	ReasonMalformedToken      = "malformed_token"
+	ReasonExpired             = "expired"
+	ReasonRevoked             = "revoked"
+	ReasonCircuitMismatch     = "circuit_mismatch"
+	ReasonCustomerNotFound    = "customer_not_found"
+	ReasonInvalidProof        = "invalid_proof"
+	ReasonPublicInputMismatch = "public_input_mismatch"
+	ReasonUnavailable         = "unavailable"
+)
+
+type VerificationResult struct {
+	Valid      bool              `json:"valid"`
+	Reason     string            `json:"reason,omitempty"`  // one of the Reason constants when not valid
+	Message    string            `json:"message,omitempty"` // detail of the reason
+	Circuit    string            `json:"circuit"`
+	Backend    string            `json:"backend,omitempty"`
+	Curve      string            `json:"curve,omitempty"`
+	CustomerId string            `json:"customerId,omitempty"`
+	Public     map[string]string `json:"public,omitempty"` // verified public inputs by name, in decimal
+	VerifiedAt string            `json:"verifiedAt"`
+	DurationMs float64           `json:"durationMs"`
+}
+
+type AggregateProofResponse struct {
+	Algo          string `json:"algo"`
+	Aggregator    string `json:"aggregator"`
+	Curve         string `json:"curve"`
+	Count         int    `json:"count"`         // proofs folded, the last one repeats up to size
+	Size          int    `json:"size"`          // proofs the aggregator verifies
+	Proof         string `json:"proof"`         // base64 of the gnark binary aggregated proof
+	PublicWitness string `json:"publicWitness"` // base64 of the gnark binary public witness
+}
+
+type AggregateVerifyResponse struct {
+	Algo   string     `json:"algo"`
+	Count  int        `json:"count"`  // proofs folded, the padding is not listed
+	Size   int        `json:"size"`   // proofs the aggregator verifies
+	Public [][]string `json:"public"` // public inputs of each folded proof, in decimal
+}
+
+type ProofCalldata struct {
+	A     [2]string    `json:"a"`
+	B     [2][2]string `json:"b"`
+	C     [2]string    `json:"c"`
+	Input []string     `json:"input"`
+}
+
+type KeyResponse struct {
+	CustomerId string `json:"customerId"`
+	PublicKey  string `json:"publicKey"`
+}
+
+type CommitmentResponse struct {
+	CustomerId string `json:"customerId"`
+	Commitment string `json:"commitment"`
+	Salt       string `json:"salt"` // hex, needed to prove the hash circuit on the customer side
 }
 
 type LoginResponse struct {
@@ -20,3 +103,33 @@ type LoginResponse struct {
 	RefreshToken    string `json:"refreshToken"`
 	RefreshExpireAt string `json:"refreshExpireAt"`
 }
+
+type MembershipRoot struct {
+	Circuit   string `json:"circuit"`
+	Curve     string `json:"curve"`
+	Root      string `json:"root"`
+	Depth     int    `json:"depth"`
+	Leaves    int    `json:"leaves"`  // customers in the tree, deleted and not enrolled ones hold a zero leaf
+	Members   int    `json:"members"` // leaves holding a commitment
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type PublicSchema struct {
+	Circuit string        `json:"circuit"`
+	Curve   string        `json:"curve"`
+	Field   string        `json:"field"`  // modulus of the scalar field, inputs are decimal below it
+	Inputs  []PublicInput `json:"inputs"` // in the order of the gnark public witness
+}
+
+type PublicInput struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // field, uint64, bool or date, a yyyymmdd integer
+	Description string `json:"description,omitempty"`
+}
+
+type ProofRevocation struct {
+	Circuit    string `json:"circuit"`
+	Nullifier  string `json:"nullifier"`            // refused from now on, whatever token carries it
+	CustomerId string `json:"customerId,omitempty"` // none for anonymous circuits
+	RevokedAt  string `json:"revokedAt"`
+}